@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// isUniqueViolation reports whether err is Postgres rejecting a duplicate of a
+// UNIQUE column. Handlers insert and map this to a conflict, instead of checking
+// for an existing row first, which races with concurrent requests.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
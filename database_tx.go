@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/pkg/errors"
+)
+
+// withTx runs fn with queries bound to a transaction. The transaction is committed
+// if fn returns nil and rolled back otherwise.
+func (cfg *apiConfig) withTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+
+	if err := fn(cfg.DB.WithTx(tx)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			cfg.Logger.Printf("Failed to roll back transaction: %+v", rollbackErr)
+		}
+		return err
+	}
+	return errors.Wrap(tx.Commit(), "committing transaction")
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/search"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const maxFilterFeedIDs = 50
+
+// postFilters holds the optional timeline filters of GET /v1/posts.
+type postFilters struct {
+	FolderID uuid.NullUUID
+	Since    sql.NullTime
+	Until    sql.NullTime
+	FeedIDs  []uuid.UUID
+	Query    sql.NullString
+	Sort     string
+}
+
+func (f postFilters) active() bool {
+	return f.FolderID.Valid || f.Since.Valid || f.Until.Valid || len(f.FeedIDs) > 0 || f.Query.Valid || f.Sort != sortPublished
+}
+
+func parsePostFilters(queries url.Values) (postFilters, error) {
+	filters := postFilters{Sort: sortPublished, FeedIDs: []uuid.UUID{}}
+
+	if folderQ := queries.Get("folder"); folderQ != "" {
+		folderID, err := uuid.Parse(folderQ)
+		if err != nil {
+			return filters, errors.New("Invalid folder")
+		}
+		filters.FolderID = uuid.NullUUID{UUID: folderID, Valid: true}
+	}
+
+	if sinceQ := queries.Get("since"); sinceQ != "" {
+		since, err := parseFilterTime(sinceQ)
+		if err != nil {
+			return filters, errors.New("Invalid since. Expected RFC 3339 time or YYYY-MM-DD date")
+		}
+		filters.Since = sql.NullTime{Time: since, Valid: true}
+	}
+
+	if untilQ := queries.Get("until"); untilQ != "" {
+		until, err := parseFilterTime(untilQ)
+		if err != nil {
+			return filters, errors.New("Invalid until. Expected RFC 3339 time or YYYY-MM-DD date")
+		}
+		filters.Until = sql.NullTime{Time: until, Valid: true}
+	}
+
+	if filters.Since.Valid && filters.Until.Valid && !filters.Since.Time.Before(filters.Until.Time) {
+		return filters, errors.New("since must be before until")
+	}
+
+	if feedIDsQ := queries.Get("feed_ids"); feedIDsQ != "" {
+		for _, id := range strings.Split(feedIDsQ, ",") {
+			feedID, err := uuid.Parse(strings.TrimSpace(id))
+			if err != nil {
+				return filters, errors.New("Invalid feed_ids. Expected comma separated feed ids")
+			}
+			filters.FeedIDs = append(filters.FeedIDs, feedID)
+		}
+		if len(filters.FeedIDs) > maxFilterFeedIDs {
+			return filters, errors.Errorf("Too many feed_ids. At most %d are allowed", maxFilterFeedIDs)
+		}
+	}
+
+	if q := queries.Get("q"); q != "" {
+		tsQuery, err := search.ParseQuery(q)
+		if err != nil {
+			return filters, errors.New("Invalid q: " + err.Error())
+		}
+		filters.Query = sql.NullString{String: tsQuery, Valid: true}
+	}
+
+	switch sort := queries.Get("sort"); sort {
+	case "", sortPublished:
+	case sortIngested:
+		filters.Sort = sortIngested
+	default:
+		return filters, errors.New("Invalid sort. Expected 'published' or 'ingested'")
+	}
+
+	return filters, nil
+}
+
+// parseFilterTime accepts a full timestamp or a plain date, which is read as
+// midnight UTC.
+func parseFilterTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
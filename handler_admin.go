@@ -0,0 +1,221 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultAdminPageSize = 50
+	maxAdminPageSize     = 100
+)
+
+// middlewareAdmin only calls handler for administrators. It goes inside middlewareAuth.
+func (cfg *apiConfig) middlewareAdmin(handler authedHandler) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, u database.User) {
+		if !u.IsAdmin {
+			respondWithError(w, http.StatusForbidden, "Administrator access required")
+			return
+		}
+		handler(w, r, u)
+	}
+}
+
+// handlerAdminUsersGet lists users, filtered by ?q= on their name and paged with
+// ?limit= and ?offset=.
+func (cfg *apiConfig) handlerAdminUsersGet(w http.ResponseWriter, r *http.Request, u database.User) {
+	queries := r.URL.Query()
+
+	limit, offset := defaultAdminPageSize, 0
+	var err error
+	if l := queries.Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxAdminPageSize {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+	if o := queries.Get("offset"); o != "" {
+		offset, err = strconv.Atoi(o)
+		if err != nil || offset < 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid offset")
+			return
+		}
+	}
+
+	q := queries.Get("q")
+	users, err := cfg.DB.GetUsers(r.Context(), database.GetUsersParams{
+		Query:     sql.NullString{String: q, Valid: q != ""},
+		RowLimit:  int32(limit),
+		RowOffset: int32(offset),
+	})
+	if err != nil {
+		cfg.Logger.Printf("Failed to get users: %+v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get users")
+		return
+	}
+
+	if users == nil {
+		users = []database.User{}
+	}
+	respondWithJSON(w, http.StatusOK, users)
+}
+
+// adminTargetUser parses the user in the URL. Administrators cannot change their
+// own account here, so they do not lock themselves out by accident.
+func (cfg *apiConfig) adminTargetUser(w http.ResponseWriter, r *http.Request, u database.User) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(chi.URLParam(r, "user_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user_id")
+		return uuid.Nil, false
+	}
+	if userID == u.ID {
+		respondWithError(w, http.StatusBadRequest, "Administrators cannot change their own account here")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// handlerAdminUsersPatch grants or revokes the admin role and disables or enables
+// the account, depending on which of is_admin and disabled are given.
+func (cfg *apiConfig) handlerAdminUsersPatch(w http.ResponseWriter, r *http.Request, u database.User) {
+	userID, ok := cfg.adminTargetUser(w, r, u)
+	if !ok {
+		return
+	}
+
+	var p struct {
+		IsAdmin  *bool `json:"is_admin"`
+		Disabled *bool `json:"disabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	if p.IsAdmin == nil && p.Disabled == nil {
+		respondWithError(w, http.StatusBadRequest, "Nothing to update. Provide is_admin or disabled")
+		return
+	}
+
+	user, err := cfg.DB.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User does not exist")
+		return
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to get user %v: %+v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+
+	if p.IsAdmin != nil {
+		user, err = cfg.DB.SetUserAdmin(r.Context(), database.SetUserAdminParams{
+			ID:        userID,
+			IsAdmin:   *p.IsAdmin,
+			UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			cfg.Logger.Printf("Failed to set admin role of user %v: %+v", userID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to update user")
+			return
+		}
+	}
+
+	// keep the original date when disabling an account that already is
+	if p.Disabled != nil && *p.Disabled != user.DisabledAt.Valid {
+		disabledAt := sql.NullTime{}
+		if *p.Disabled {
+			disabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+		user, err = cfg.DB.SetUserDisabled(r.Context(), database.SetUserDisabledParams{
+			ID:         userID,
+			DisabledAt: disabledAt,
+			UpdatedAt:  time.Now(),
+		})
+		if err != nil {
+			cfg.Logger.Printf("Failed to set disabled state of user %v: %+v", userID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to update user")
+			return
+		}
+	}
+
+	cfg.Logger.Printf("Admin %v updated user %v: is_admin=%v disabled=%v", u.ID, userID, user.IsAdmin, user.DisabledAt.Valid)
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// handlerAdminUsersDelete deletes a user. The schema cascades the deletion to
+// everything they own, including the feeds they added.
+func (cfg *apiConfig) handlerAdminUsersDelete(w http.ResponseWriter, r *http.Request, u database.User) {
+	userID, ok := cfg.adminTargetUser(w, r, u)
+	if !ok {
+		return
+	}
+
+	deleted, err := cfg.DB.DeleteUser(r.Context(), userID)
+	if err != nil {
+		cfg.Logger.Printf("Failed to delete user %v: %+v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete user")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "User does not exist")
+		return
+	}
+
+	cfg.Logger.Printf("Admin %v deleted user %v", u.ID, userID)
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handlerAdminFeedOwnerPut makes another user the owner of a feed, for example
+// before deleting the user who added it.
+func (cfg *apiConfig) handlerAdminFeedOwnerPut(w http.ResponseWriter, r *http.Request, u database.User) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "feed_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid feed_id")
+		return
+	}
+
+	var p struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	if _, err := cfg.DB.GetUserByID(r.Context(), p.UserID); err != nil {
+		respondWithError(w, http.StatusBadRequest, "User does not exist")
+		return
+	}
+
+	feed, err := cfg.DB.SetFeedOwner(r.Context(), database.SetFeedOwnerParams{
+		ID:        feedID,
+		UserID:    p.UserID,
+		UpdatedAt: time.Now(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Feed does not exist")
+		return
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to set owner of feed %v: %+v", feedID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to reassign feed")
+		return
+	}
+
+	cfg.Logger.Printf("Admin %v made user %v the owner of feed %v", u.ID, p.UserID, feedID)
+	respondWithJSON(w, http.StatusOK, feed)
+}
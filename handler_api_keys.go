@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/auth"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultRotationGrace = 24 * time.Hour
+	maxRotationGrace     = 7 * 24 * time.Hour
+)
+
+// apiKeyExpired reports whether key can no longer be used, either because its
+// expiry date passed or because it is older than API_KEY_MAX_LIFETIME.
+func (cfg *apiConfig) apiKeyExpired(key database.ApiKey, now time.Time) bool {
+	if key.ExpiresAt.Valid && !now.Before(key.ExpiresAt.Time) {
+		return true
+	}
+	return cfg.APIKeyMaxLifetime > 0 && !now.Before(key.CreatedAt.Add(cfg.APIKeyMaxLifetime))
+}
+
+// defaultApiKeyExpiry is the expiry of keys created without one.
+func (cfg *apiConfig) defaultApiKeyExpiry() sql.NullTime {
+	if cfg.APIKeyMaxLifetime <= 0 {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: time.Now().Add(cfg.APIKeyMaxLifetime), Valid: true}
+}
+
+// createApiKey issues a new key for the user and returns its row and the plaintext
+// key, which cannot be recovered later.
+func (cfg *apiConfig) createApiKey(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt sql.NullTime) (database.ApiKey, string, error) {
+	newKey, err := auth.GenerateAPIKey()
+	if err != nil {
+		return database.ApiKey{}, "", err
+	}
+
+	apiKey, err := cfg.DB.CreateApiKey(ctx, database.CreateApiKeyParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    newKey.Prefix,
+		KeyHash:   newKey.Hash,
+		ExpiresAt: expiresAt,
+		Scopes:    scopes,
+	})
+	if err != nil {
+		return database.ApiKey{}, "", err
+	}
+	return apiKey, newKey.Key, nil
+}
+
+// handlerApiKeysPost creates a key limited to the requested scopes. Only the
+// bootstrap key returned by POST /v1/users is created with admin implicitly,
+// every other key has to name its scopes.
+func (cfg *apiConfig) handlerApiKeysPost(w http.ResponseWriter, r *http.Request, u database.User) {
+	var k struct {
+		Name string `json:"name"`
+		// Scopes are required, so a key never gets full access by omission
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&k); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	name := strings.TrimSpace(k.Name)
+	if name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	scopes := k.Scopes
+	if len(scopes) == 0 {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("scopes is required. Valid scopes are %s", strings.Join(auth.Scopes, ", ")))
+		return
+	}
+	for _, scope := range scopes {
+		if !auth.ValidScope(scope) {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Unknown scope %q. Valid scopes are %s", scope, strings.Join(auth.Scopes, ", ")))
+			return
+		}
+	}
+
+	expiresAt := cfg.defaultApiKeyExpiry()
+	if k.ExpiresAt != nil {
+		if !k.ExpiresAt.After(time.Now()) {
+			respondWithError(w, http.StatusBadRequest, "expires_at must be in the future")
+			return
+		}
+		if expiresAt.Valid && k.ExpiresAt.After(expiresAt.Time) {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("expires_at must be within %v", cfg.APIKeyMaxLifetime))
+			return
+		}
+		expiresAt = sql.NullTime{Time: *k.ExpiresAt, Valid: true}
+	}
+
+	apiKey, key, err := cfg.createApiKey(r.Context(), u.ID, name, scopes, expiresAt)
+	if err != nil {
+		cfg.Logger.Printf("Failed to create API key for user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, struct {
+		database.ApiKey
+		Key string `json:"key"`
+	}{
+		ApiKey: apiKey,
+		Key:    key,
+	})
+}
+
+func (cfg *apiConfig) handlerApiKeysGet(w http.ResponseWriter, r *http.Request, u database.User) {
+	apiKeys, err := cfg.DB.GetApiKeysByUser(r.Context(), u.ID)
+	if err != nil {
+		cfg.Logger.Printf("Failed to get API keys for user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get API keys")
+		return
+	}
+
+	if apiKeys == nil {
+		apiKeys = []database.ApiKey{}
+	}
+	respondWithJSON(w, http.StatusOK, apiKeys)
+}
+
+// handlerApiKeysDelete revokes a key. Revoked keys stay listed with revoked_at set.
+func (cfg *apiConfig) handlerApiKeysDelete(w http.ResponseWriter, r *http.Request, u database.User) {
+	apiKeyID, err := uuid.Parse(chi.URLParam(r, "api_key_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid api_key_id")
+		return
+	}
+
+	revoked, err := cfg.DB.RevokeApiKey(r.Context(), database.RevokeApiKeyParams{
+		ID:        apiKeyID,
+		UserID:    u.ID,
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		cfg.Logger.Printf("Failed to revoke API key %v: %+v", apiKeyID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "API key does not exist")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handlerApiKeysRotate replaces a key with a new one of the same name and scopes.
+// The old key keeps working for a grace window, grace_seconds in the body or a day
+// by default, so clients can be switched over without downtime.
+func (cfg *apiConfig) handlerApiKeysRotate(w http.ResponseWriter, r *http.Request, u database.User) {
+	apiKeyID, err := uuid.Parse(chi.URLParam(r, "api_key_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid api_key_id")
+		return
+	}
+
+	var k struct {
+		GraceSeconds *int `json:"grace_seconds"`
+	}
+	// the body is optional
+	if err := json.NewDecoder(r.Body).Decode(&k); err != nil && !errors.Is(err, io.EOF) {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	grace := defaultRotationGrace
+	if k.GraceSeconds != nil {
+		grace = time.Duration(*k.GraceSeconds) * time.Second
+		if grace < 0 || grace > maxRotationGrace {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("grace_seconds must be between 0 and %d", int(maxRotationGrace.Seconds())))
+			return
+		}
+	}
+
+	old, err := cfg.DB.GetApiKeyByID(r.Context(), database.GetApiKeyByIDParams{ID: apiKeyID, UserID: u.ID})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "API key does not exist")
+		return
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to get API key %v: %+v", apiKeyID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to rotate API key")
+		return
+	}
+	now := time.Now()
+	if old.RevokedAt.Valid || old.ReplacedBy.Valid || cfg.apiKeyExpired(old, now) {
+		respondWithError(w, http.StatusConflict, "API key is revoked, expired or already rotated")
+		return
+	}
+
+	// the new key lives as long as the old one was meant to
+	expiresAt := cfg.defaultApiKeyExpiry()
+	if old.ExpiresAt.Valid {
+		lifetime := old.ExpiresAt.Time.Sub(old.CreatedAt)
+		if !expiresAt.Valid || now.Add(lifetime).Before(expiresAt.Time) {
+			expiresAt = sql.NullTime{Time: now.Add(lifetime), Valid: true}
+		}
+	}
+
+	apiKey, key, err := cfg.createApiKey(r.Context(), u.ID, old.Name, old.Scopes, expiresAt)
+	if err != nil {
+		cfg.Logger.Printf("Failed to create API key for user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to rotate API key")
+		return
+	}
+
+	replaced, err := cfg.DB.ReplaceApiKey(r.Context(), database.ReplaceApiKeyParams{
+		ReplacedBy: uuid.NullUUID{UUID: apiKey.ID, Valid: true},
+		GraceUntil: sql.NullTime{Time: now.Add(grace), Valid: true},
+		UpdatedAt:  now,
+		ID:         old.ID,
+		UserID:     u.ID,
+	})
+	if err != nil || replaced == 0 {
+		// drop the new key, nobody has seen it
+		if _, revokeErr := cfg.DB.RevokeApiKey(r.Context(), database.RevokeApiKeyParams{
+			ID:        apiKey.ID,
+			UserID:    u.ID,
+			RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		}); revokeErr != nil {
+			cfg.Logger.Printf("Failed to revoke unused API key %v: %+v", apiKey.ID, revokeErr)
+		}
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to replace API key %v: %+v", old.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to rotate API key")
+		return
+	}
+	if replaced == 0 {
+		// a concurrent rotation or revocation won
+		respondWithError(w, http.StatusConflict, "API key is revoked, expired or already rotated")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, struct {
+		database.ApiKey
+		Key string `json:"key"`
+	}{
+		ApiKey: apiKey,
+		Key:    key,
+	})
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/auth"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	sessionCookieName = "session"
+	// sessions expire this long after logging in
+	sessionTTL = 14 * 24 * time.Hour
+)
+
+type credentials struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+// sessionResponse is returned when a session starts. The CSRF token must be sent
+// back in the X-CSRF-Token header of every request that changes something.
+type sessionResponse struct {
+	User      database.User `json:"user"`
+	CSRFToken string        `json:"csrf_token"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+// handlerAuthRegister creates an account with a password and logs it in.
+func (cfg *apiConfig) handlerAuthRegister(w http.ResponseWriter, r *http.Request) {
+	var c credentials
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	if c.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+	if len(c.Password) < auth.MinPasswordLength || len(c.Password) > auth.MaxPasswordLength {
+		respondWithError(w, http.StatusBadRequest, "Password must be between 8 and 72 characters long")
+		return
+	}
+
+	passwordHash, err := auth.HashPassword(c.Password)
+	if err != nil {
+		cfg.Logger.Printf("Failed to hash password: %+v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create user")
+		return
+	}
+
+	user, err := cfg.DB.CreateUser(r.Context(), database.CreateUserParams{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Name:         c.Name,
+		PasswordHash: sql.NullString{String: passwordHash, Valid: true},
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "User already exists")
+		return
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to create user: %+v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create user")
+		return
+	}
+
+	cfg.startSession(w, r, user, http.StatusCreated)
+}
+
+func (cfg *apiConfig) handlerAuthLogin(w http.ResponseWriter, r *http.Request) {
+	var c credentials
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	user, err := cfg.DB.GetUserByName(r.Context(), c.Name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		cfg.Logger.Printf("Failed to get user %q: %+v", c.Name, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+	// unknown users are checked against a dummy hash, so they cannot be told apart by timing
+	if !auth.CheckPassword(user.PasswordHash.String, c.Password) {
+		respondWithError(w, http.StatusUnauthorized, "Invalid name or password")
+		return
+	}
+	if user.DisabledAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Account is disabled")
+		return
+	}
+
+	cfg.startSession(w, r, user, http.StatusOK)
+}
+
+// handlerAuthSession returns the current session, so the webui can recover its
+// CSRF token after a reload.
+func (cfg *apiConfig) handlerAuthSession(w http.ResponseWriter, r *http.Request, u database.User) {
+	session, ok := auth.SessionFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Request is not authenticated with a session")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, sessionResponse{
+		User:      u,
+		CSRFToken: session.CsrfToken,
+		ExpiresAt: session.ExpiresAt,
+	})
+}
+
+func (cfg *apiConfig) handlerAuthLogout(w http.ResponseWriter, r *http.Request, u database.User) {
+	session, ok := auth.SessionFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Request is not authenticated with a session")
+		return
+	}
+
+	if err := cfg.DB.DeleteSession(r.Context(), session.ID); err != nil {
+		cfg.Logger.Printf("Failed to delete session %v: %+v", session.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+
+	cfg.clearSessionCookie(w)
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (cfg *apiConfig) startSession(w http.ResponseWriter, r *http.Request, user database.User, status int) {
+	session, err := cfg.createSession(w, r, user)
+	if err != nil {
+		cfg.Logger.Printf("Failed to create session for user %v: %+v", user.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+
+	respondWithJSON(w, status, sessionResponse{
+		User:      user,
+		CSRFToken: session.CsrfToken,
+		ExpiresAt: session.ExpiresAt,
+	})
+}
+
+// createSession stores a new session for user and sets its cookie on w.
+func (cfg *apiConfig) createSession(w http.ResponseWriter, r *http.Request, user database.User) (database.Session, error) {
+	newSession, err := auth.GenerateSession()
+	if err != nil {
+		return database.Session{}, err
+	}
+
+	now := time.Now()
+	session, err := cfg.DB.CreateSession(r.Context(), database.CreateSessionParams{
+		ID:         uuid.New(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		UserID:     user.ID,
+		TokenHash:  newSession.Hash,
+		CsrfToken:  newSession.CSRFToken,
+		ExpiresAt:  now.Add(sessionTTL),
+		LastSeenAt: now,
+	})
+	if err != nil {
+		return database.Session{}, errors.Wrap(err, "creating session")
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    newSession.Token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   cfg.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return session, nil
+}
+
+func (cfg *apiConfig) clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   cfg.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// authenticateSession calls handler with the user of the session cookie. Requests
+// that change something must carry the session's CSRF token in X-CSRF-Token.
+func (cfg *apiConfig) authenticateSession(w http.ResponseWriter, r *http.Request, token string, handler authedHandler) {
+	now := time.Now()
+	session, err := cfg.DB.GetSessionByTokenHash(r.Context(), database.GetSessionByTokenHashParams{
+		TokenHash: auth.HashSessionToken(token),
+		Now:       now,
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			cfg.Logger.Printf("Failed to get session: %+v", err)
+		}
+		cfg.clearSessionCookie(w)
+		respondWithError(w, http.StatusUnauthorized, "Session has expired. Please log in again")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		csrfToken := r.Header.Get("X-CSRF-Token")
+		if subtle.ConstantTimeCompare([]byte(csrfToken), []byte(session.CsrfToken)) != 1 {
+			respondWithError(w, http.StatusForbidden, "Missing or invalid X-CSRF-Token header")
+			return
+		}
+	}
+
+	user, err := cfg.DB.GetUserByID(r.Context(), session.UserID)
+	if err != nil {
+		cfg.Logger.Printf("Failed to get user %v for session %v: %+v", session.UserID, session.ID, err)
+		respondWithError(w, http.StatusUnauthorized, "Session has expired. Please log in again")
+		return
+	}
+	if user.DisabledAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Account is disabled")
+		return
+	}
+
+	if err := cfg.DB.TouchSession(r.Context(), database.TouchSessionParams{
+		Now: now,
+		ID:  session.ID,
+	}); err != nil {
+		cfg.Logger.Printf("Failed to record use of session %v: %+v", session.ID, err)
+	}
+
+	handler(w, r.WithContext(auth.WithSession(r.Context(), session)), user)
+}
+
+// PruneSessions deletes expired sessions every interval until ctx is done.
+func (cfg *apiConfig) PruneSessions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := cfg.DB.DeleteExpiredSessions(ctx, time.Now())
+			if err != nil {
+				cfg.Logger.Printf("Failed to delete expired sessions: %+v", err)
+			} else if deleted > 0 {
+				cfg.Logger.Printf("Deleted %d expired sessions", deleted)
+			}
+		}
+	}
+}
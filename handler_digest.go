@@ -0,0 +1,148 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/digest"
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+)
+
+// defaultDigestSettings is what a user without a digest_settings row gets.
+func defaultDigestSettings(u database.User) database.DigestSetting {
+	return database.DigestSetting{
+		UserID:      u.ID,
+		Frequency:   digest.FrequencyOff,
+		Timezone:    "UTC",
+		SendHour:    8,
+		SendWeekday: int32(time.Monday),
+	}
+}
+
+func (cfg *apiConfig) handlerDigestGet(w http.ResponseWriter, r *http.Request, u database.User) {
+	settings, err := cfg.DB.GetDigestSettings(r.Context(), u.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		settings, err = defaultDigestSettings(u), nil
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to get digest settings for user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get digest settings")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, settings)
+}
+
+// handlerDigestPut replaces the caller's digest settings. Fields left out of the
+// body keep their current value.
+func (cfg *apiConfig) handlerDigestPut(w http.ResponseWriter, r *http.Request, u database.User) {
+	current, err := cfg.DB.GetDigestSettings(r.Context(), u.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		current, err = defaultDigestSettings(u), nil
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to get digest settings for user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update digest settings")
+		return
+	}
+
+	body := struct {
+		Email       string `json:"email"`
+		Frequency   string `json:"frequency"`
+		Timezone    string `json:"timezone"`
+		SendHour    int32  `json:"send_hour"`
+		SendWeekday int32  `json:"send_weekday"`
+	}{
+		Email:       current.Email,
+		Frequency:   current.Frequency,
+		Timezone:    current.Timezone,
+		SendHour:    current.SendHour,
+		SendWeekday: current.SendWeekday,
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	addr, err := mail.ParseAddress(strings.TrimSpace(body.Email))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "A valid email is required")
+		return
+	}
+	switch body.Frequency {
+	case digest.FrequencyOff, digest.FrequencyDaily, digest.FrequencyWeekly:
+	default:
+		respondWithError(w, http.StatusBadRequest, "frequency must be one of off, daily or weekly")
+		return
+	}
+	if _, err := time.LoadLocation(body.Timezone); err != nil || body.Timezone == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid timezone. Use an IANA name like Europe/Berlin")
+		return
+	}
+	if body.SendHour < 0 || body.SendHour > 23 {
+		respondWithError(w, http.StatusBadRequest, "send_hour must be between 0 and 23")
+		return
+	}
+	if body.SendWeekday < 0 || body.SendWeekday > 6 {
+		respondWithError(w, http.StatusBadRequest, "send_weekday must be between 0 (Sunday) and 6 (Saturday)")
+		return
+	}
+
+	token := current.UnsubscribeToken
+	if token == "" {
+		token, err = generateToken(32)
+		if err != nil {
+			cfg.Logger.Printf("Failed to generate unsubscribe token: %+v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to update digest settings")
+			return
+		}
+	}
+
+	settings, err := cfg.DB.UpsertDigestSettings(r.Context(), database.UpsertDigestSettingsParams{
+		UserID:           u.ID,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+		Email:            addr.Address,
+		Frequency:        body.Frequency,
+		Timezone:         body.Timezone,
+		SendHour:         body.SendHour,
+		SendWeekday:      body.SendWeekday,
+		UnsubscribeToken: token,
+	})
+	if err != nil {
+		cfg.Logger.Printf("Failed to update digest settings for user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update digest settings")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, settings)
+}
+
+// handlerDigestUnsubscribe turns digests off. It is public and linked from every
+// digest; POST supports one-click List-Unsubscribe from mail clients.
+func (cfg *apiConfig) handlerDigestUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	updated, err := cfg.DB.UnsubscribeDigest(r.Context(), database.UnsubscribeDigestParams{
+		UnsubscribeToken: chi.URLParam(r, "token"),
+		UpdatedAt:        time.Now(),
+	})
+	if err != nil {
+		cfg.Logger.Printf("Failed to unsubscribe from digest: %+v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to unsubscribe")
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, http.StatusNotFound, "Unknown unsubscribe link")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "unsubscribed"})
+}
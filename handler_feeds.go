@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/jobs"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// managedFeed loads the feed in the URL if u may change it, which is its owner or
+// an administrator.
+func (cfg *apiConfig) managedFeed(w http.ResponseWriter, r *http.Request, u database.User) (database.Feed, bool) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "feed_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid feed_id")
+		return database.Feed{}, false
+	}
+
+	feed, err := cfg.DB.GetFeedByID(r.Context(), feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Feed does not exist")
+		return database.Feed{}, false
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to get feed %v: %+v", feedID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get feed")
+		return database.Feed{}, false
+	}
+
+	if feed.UserID != u.ID && !u.IsAdmin {
+		respondWithError(w, http.StatusForbidden, "Only the owner of the feed can change it")
+		return database.Feed{}, false
+	}
+	return feed, true
+}
+
+// handlerFeedsPatch changes the title or the URL of a feed. A title replaces the
+// channel title until it is set to null. A new URL, or a removed title, puts the
+// feed back to pending and queues a create_feed job that validates it and fetches
+// its channel title, like adding a feed. The response then carries the job.
+func (cfg *apiConfig) handlerFeedsPatch(w http.ResponseWriter, r *http.Request, u database.User) {
+	feed, ok := cfg.managedFeed(w, r, u)
+	if !ok {
+		return
+	}
+	if feed.Status == feedStatusPending {
+		respondWithError(w, http.StatusConflict, "Feed is still being validated")
+		return
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	params := database.UpdateFeedParams{
+		ID:            feed.ID,
+		Url:           feed.Url,
+		Title:         feed.Title,
+		TitleOverride: feed.TitleOverride,
+		Description:   feed.Description,
+		Link:          feed.Link,
+	}
+
+	if raw, ok := body["title"]; ok {
+		var title *string
+		if err := json.Unmarshal(raw, &title); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid title")
+			return
+		}
+		params.TitleOverride = sql.NullString{}
+		if title != nil && strings.TrimSpace(*title) != "" {
+			params.TitleOverride = sql.NullString{String: strings.TrimSpace(*title), Valid: true}
+			params.Title = params.TitleOverride.String
+		}
+	}
+
+	urlChanged := false
+	if raw, ok := body["url"]; ok {
+		if err := json.Unmarshal(raw, &params.Url); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid url")
+			return
+		}
+		if parsedURL, err := url.ParseRequestURI(params.Url); err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+			respondWithError(w, http.StatusBadRequest, "url must be an absolute http or https URL")
+			return
+		}
+		urlChanged = params.Url != feed.Url
+	}
+
+	// the channel title is needed again when the URL changes or the override is removed
+	revalidate := urlChanged || (feed.TitleOverride.Valid && !params.TitleOverride.Valid)
+	params.UpdatedAt = time.Now()
+
+	var updated database.Feed
+	err := cfg.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		updated, err = q.UpdateFeed(r.Context(), params)
+		if err != nil {
+			return err
+		}
+		if !revalidate {
+			return nil
+		}
+
+		if urlChanged {
+			// the hub subscription is for the old URL, the create_feed job subscribes
+			// again if the new one advertises a hub
+			if err := q.DeleteWebsubSubscription(r.Context(), feed.ID); err != nil {
+				return errors.Wrap(err, "deleting WebSub subscription")
+			}
+		}
+		updated, err = q.SetFeedStatus(r.Context(), database.SetFeedStatusParams{
+			ID:        feed.ID,
+			Status:    feedStatusPending,
+			UpdatedAt: time.Now(),
+		})
+		return errors.Wrap(err, "setting feed status")
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Another feed already has this url")
+		return
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to update feed %v: %+v", feed.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update feed")
+		return
+	}
+
+	if !revalidate {
+		respondWithJSON(w, http.StatusOK, updated)
+		return
+	}
+
+	job, err := cfg.Jobs.Enqueue(r.Context(), jobs.KindCreateFeed, u.ID, updated.ID)
+	if err != nil {
+		cfg.Logger.Printf("Failed to queue validation of feed %v: %+v", updated.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update feed")
+		return
+	}
+	if urlChanged {
+		cfg.Logger.Printf("User %v moved feed %v from %v to %v", u.ID, feed.ID, feed.Url, updated.Url)
+	}
+
+	respondWithJSON(w, http.StatusAccepted, struct {
+		Feed database.Feed `json:"feed"`
+		Job  database.Job  `json:"job"`
+	}{
+		Feed: updated,
+		Job:  job,
+	})
+}
+
+// handlerFeedsDelete removes a feed for its owner. If other users follow it the
+// feed stays for them: its oldest follower becomes the owner and only the owner's
+// follow is removed. Otherwise the feed is deleted with its posts. Administrators
+// delete feeds of other users for everyone, and their own with ?purge=true.
+func (cfg *apiConfig) handlerFeedsDelete(w http.ResponseWriter, r *http.Request, u database.User) {
+	feed, ok := cfg.managedFeed(w, r, u)
+	if !ok {
+		return
+	}
+
+	purge := u.IsAdmin && (feed.UserID != u.ID || r.URL.Query().Get("purge") == "true")
+	if !purge {
+		var newOwner uuid.UUID
+		transferred := false
+		// the owner stops following only if the feed found a new owner
+		err := cfg.withTx(r.Context(), func(q *database.Queries) error {
+			var err error
+			newOwner, transferred, err = handOverFeed(r.Context(), q, feed)
+			if err != nil || !transferred {
+				return err
+			}
+			return errors.Wrap(q.DeleteFeedFollow(r.Context(), database.DeleteFeedFollowParams{
+				FeedID: feed.ID,
+				UserID: feed.UserID,
+			}), "unfollowing feed")
+		})
+		if err != nil {
+			cfg.Logger.Printf("Failed to transfer feed %v: %+v", feed.ID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete feed")
+			return
+		}
+
+		if transferred {
+			cfg.Logger.Printf("User %v gave feed %v to its follower %v", u.ID, feed.ID, newOwner)
+			respondWithJSON(w, http.StatusOK, map[string]string{
+				"status":   "transferred",
+				"owner_id": newOwner.String(),
+			})
+			return
+		}
+	}
+
+	// follows, posts, jobs and subscriptions of the feed cascade
+	if _, err := cfg.DB.DeleteFeed(r.Context(), feed.ID); err != nil {
+		cfg.Logger.Printf("Failed to delete feed %v: %+v", feed.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete feed")
+		return
+	}
+
+	cfg.Logger.Printf("User %v deleted feed %v", u.ID, feed.ID)
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handOverFeed makes the oldest follower of feed other than its owner the new
+// owner. It returns false if nobody else follows the feed.
+func handOverFeed(ctx context.Context, q *database.Queries, feed database.Feed) (uuid.UUID, bool, error) {
+	newOwner, err := q.GetOldestOtherFollower(ctx, database.GetOldestOtherFollowerParams{
+		FeedID: feed.ID,
+		UserID: feed.UserID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, errors.Wrap(err, "getting followers")
+	}
+
+	if _, err := q.SetFeedOwner(ctx, database.SetFeedOwnerParams{
+		ID:        feed.ID,
+		UserID:    newOwner,
+		UpdatedAt: time.Now(),
+	}); err != nil {
+		return uuid.Nil, false, errors.Wrapf(err, "transferring feed to user %v", newOwner)
+	}
+	return newOwner, true, nil
+}
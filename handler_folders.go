@@ -0,0 +1,176 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerFoldersGet(w http.ResponseWriter, r *http.Request, u database.User) {
+	folders, err := cfg.DB.GetFoldersByUser(r.Context(), u.ID)
+	if err != nil {
+		cfg.Logger.Printf("Failed to get folders for user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get folders")
+		return
+	}
+	if folders == nil {
+		folders = []database.Folder{}
+	}
+
+	respondWithJSON(w, http.StatusOK, folders)
+}
+
+func (cfg *apiConfig) handlerFoldersPost(w http.ResponseWriter, r *http.Request, u database.User) {
+	var f struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	name := strings.TrimSpace(f.Name)
+	if name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	folder, err := cfg.DB.CreateFolder(r.Context(), database.CreateFolderParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    u.ID,
+		Name:      name,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Folder already exists")
+		return
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to create folder: %+v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create folder")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, folder)
+}
+
+func (cfg *apiConfig) handlerFoldersPatch(w http.ResponseWriter, r *http.Request, u database.User) {
+	folderID, err := uuid.Parse(chi.URLParam(r, "folder_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid folder_id")
+		return
+	}
+
+	var f struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	name := strings.TrimSpace(f.Name)
+	if name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	folder, err := cfg.DB.UpdateFolder(r.Context(), database.UpdateFolderParams{
+		ID:        folderID,
+		UserID:    u.ID,
+		Name:      name,
+		UpdatedAt: time.Now(),
+	})
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Folder does not exist")
+		return
+	}
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Folder already exists")
+		return
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to update folder %v: %+v", folderID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update folder")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, folder)
+}
+
+func (cfg *apiConfig) handlerFoldersDelete(w http.ResponseWriter, r *http.Request, u database.User) {
+	folderID, err := uuid.Parse(chi.URLParam(r, "folder_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid folder_id")
+		return
+	}
+
+	// check if folder exists
+	if _, err := cfg.DB.GetFolderByID(r.Context(), database.GetFolderByIDParams{ID: folderID, UserID: u.ID}); err != nil {
+		respondWithError(w, http.StatusNotFound, "Folder does not exist")
+		return
+	}
+
+	// feed follows in the folder are kept and become unassigned
+	if err := cfg.DB.DeleteFolder(r.Context(), database.DeleteFolderParams{ID: folderID, UserID: u.ID}); err != nil {
+		cfg.Logger.Printf("Failed to delete folder %v: %+v", folderID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete folder")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handlerUnreadCountsGet returns how many posts of each followed feed arrived
+// since the feed was last marked as read. Like the timeline it leaves hidden
+// feeds out and takes a folder to count only the feeds in that folder.
+func (cfg *apiConfig) handlerUnreadCountsGet(w http.ResponseWriter, r *http.Request, u database.User) {
+	var folderID uuid.NullUUID
+	if folderQ := r.URL.Query().Get("folder"); folderQ != "" {
+		id, err := uuid.Parse(folderQ)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid folder")
+			return
+		}
+		folderID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	counts, err := cfg.DB.GetUnreadCountsByUser(r.Context(), database.GetUnreadCountsByUserParams{
+		UserID:   u.ID,
+		FolderID: folderID,
+	})
+	if err != nil {
+		cfg.Logger.Printf("Failed to get unread counts for user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get unread counts")
+		return
+	}
+	if counts == nil {
+		counts = []database.GetUnreadCountsByUserRow{}
+	}
+
+	var total int64
+	for _, c := range counts {
+		total += c.Unread
+	}
+
+	respondWithJSON(w, http.StatusOK, struct {
+		Total int64                               `json:"total"`
+		Feeds []database.GetUnreadCountsByUserRow `json:"feeds"`
+	}{
+		Total: total,
+		Feeds: counts,
+	})
+}
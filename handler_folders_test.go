@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/auth"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestUnreadCountsByFolder(t *testing.T) {
+	cfg := newTestConfig(t)
+	router := v1Router(cfg)
+	ctx := context.Background()
+
+	user := createTestUser(t, cfg, "reader")
+	_, key, err := cfg.createApiKey(ctx, user.ID, "test", []string{auth.ScopePostsRead, auth.ScopeFollowsWrite}, sql.NullTime{})
+	if err != nil {
+		t.Fatalf("creating API key: %v", err)
+	}
+	infra := createTestFeed(t, cfg, user, "https://example.com/infra.xml")
+	golang := createTestFeed(t, cfg, user, "https://example.com/go.xml")
+
+	folder, err := cfg.DB.CreateFolder(ctx, database.CreateFolderParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      "infra",
+	})
+	if err != nil {
+		t.Fatalf("creating folder: %v", err)
+	}
+	if _, err := cfg.DB.UpdateFeedFollow(ctx, database.UpdateFeedFollowParams{
+		FeedID:    infra.ID,
+		UserID:    user.ID,
+		FolderID:  uuid.NullUUID{UUID: folder.ID, Valid: true},
+		Notify:    true,
+		UpdatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("moving feed into folder: %v", err)
+	}
+
+	// both feeds were read an hour ago, the posts below arrive after that
+	for _, feed := range []database.Feed{infra, golang} {
+		if _, err := cfg.DB.MarkFeedFollowRead(ctx, database.MarkFeedFollowReadParams{
+			FeedID:     feed.ID,
+			UserID:     user.ID,
+			LastReadAt: time.Now().Add(-time.Hour),
+		}); err != nil {
+			t.Fatalf("setting read marker: %v", err)
+		}
+	}
+	for i, feed := range []database.Feed{infra, infra, golang} {
+		if _, err := cfg.DB.CreatePost(ctx, database.CreatePostParams{
+			ID:          uuid.New(),
+			CreatedAt:   time.Now().Add(-time.Minute),
+			UpdatedAt:   time.Now(),
+			FeedID:      feed.ID,
+			Title:       "post",
+			Url:         feed.Url + "#" + string(rune('a'+i)),
+			PublishDate: time.Now(),
+		}); err != nil {
+			t.Fatalf("creating post: %v", err)
+		}
+	}
+
+	unread := func(query string) int64 {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/unread_counts"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("getting unread counts: got %d %s", rec.Code, rec.Body)
+		}
+		var body struct {
+			Total int64 `json:"total"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding unread counts: %v", err)
+		}
+		return body.Total
+	}
+
+	if got := unread(""); got != 3 {
+		t.Errorf("unread posts = %d, want 3", got)
+	}
+	if got := unread("?folder=" + folder.ID.String()); got != 2 {
+		t.Errorf("unread posts in folder = %d, want 2", got)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/feed_follows/"+infra.ID.String()+"/read", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("marking feed as read: got %d %s", rec.Code, rec.Body)
+	}
+	if got := unread("?folder=" + folder.ID.String()); got != 0 {
+		t.Errorf("unread posts in folder after reading = %d, want 0", got)
+	}
+	if got := unread(""); got != 1 {
+		t.Errorf("unread posts after reading the folder = %d, want 1", got)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/jobs"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/scrapper"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// a feed is refreshed at most once per feedRefreshCooldown, whoever asks
+	feedRefreshCooldown = time.Minute
+	// and each user may ask for userRefreshLimit refreshes per userRefreshWindow
+	userRefreshLimit  = 30
+	userRefreshWindow = time.Hour
+)
+
+// handlerFeedRefreshPost queues an immediate scrape of a followed feed and
+// returns the job to poll at GET /v1/jobs/{job_id}.
+func (cfg *apiConfig) handlerFeedRefreshPost(w http.ResponseWriter, r *http.Request, u database.User) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "feed_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid feed_id")
+		return
+	}
+
+	feed, err := cfg.DB.GetFeedByID(r.Context(), feedID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Feed does not exist")
+		return
+	}
+	if feed.Status != feedStatusActive {
+		respondWithError(w, http.StatusConflict, "Only active feeds can be refreshed")
+		return
+	}
+	if _, err := cfg.DB.GetFeedFollows(r.Context(), database.GetFeedFollowsParams{FeedID: feedID, UserID: u.ID}); err != nil {
+		respondWithError(w, http.StatusForbidden, "Only followers of a feed can refresh it")
+		return
+	}
+
+	// the checks and the insert run under a per-user lock, and the unique index on
+	// refreshes in progress stops other users queueing the same feed concurrently
+	var job database.Job
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		if err := q.LockUserJobs(r.Context(), u.ID); err != nil {
+			return errors.Wrap(err, "locking jobs")
+		}
+
+		latest, err := q.GetLatestJobForFeed(r.Context(), database.GetLatestJobForFeedParams{FeedID: feedID, Kind: jobs.KindRefresh})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return errors.Wrap(err, "getting latest refresh")
+		}
+		if err == nil {
+			if latest.Status == jobs.StatusQueued || latest.Status == jobs.StatusRunning {
+				return refreshLimitError{feedRefreshCooldown, "A refresh of this feed is already in progress"}
+			}
+			if wait := time.Until(latest.CreatedAt.Add(feedRefreshCooldown)); wait > 0 {
+				return refreshLimitError{wait, "This feed was refreshed recently"}
+			}
+		}
+
+		count, err := q.CountJobsByUserSince(r.Context(), database.CountJobsByUserSinceParams{
+			UserID:    u.ID,
+			Kind:      jobs.KindRefresh,
+			CreatedAt: time.Now().Add(-userRefreshWindow),
+		})
+		if err != nil {
+			return errors.Wrap(err, "counting refreshes")
+		}
+		if count >= userRefreshLimit {
+			return refreshLimitError{feedRefreshCooldown, "Too many refreshes, try again later"}
+		}
+
+		job, err = q.CreateJob(r.Context(), database.CreateJobParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    u.ID,
+			FeedID:    feedID,
+			Kind:      jobs.KindRefresh,
+		})
+		return err
+	})
+	var limitErr refreshLimitError
+	if errors.As(err, &limitErr) {
+		respondTooManyRequests(w, limitErr.wait, limitErr.message)
+		return
+	}
+	if isUniqueViolation(err) {
+		respondTooManyRequests(w, feedRefreshCooldown, "A refresh of this feed is already in progress")
+		return
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to queue refresh of feed %v: %+v", feedID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to refresh feed")
+		return
+	}
+	cfg.Jobs.Wake()
+
+	w.Header().Set("Location", "/v1/jobs/"+job.ID.String())
+	respondWithJSON(w, http.StatusAccepted, job)
+}
+
+// refreshLimitError rejects a refresh with a 429 telling the client when to retry.
+type refreshLimitError struct {
+	wait    time.Duration
+	message string
+}
+
+func (e refreshLimitError) Error() string {
+	return e.message
+}
+
+func respondTooManyRequests(w http.ResponseWriter, retryAfter time.Duration, message string) {
+	seconds := int(retryAfter.Round(time.Second).Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	respondWithError(w, http.StatusTooManyRequests, message)
+}
+
+func (cfg *apiConfig) handlerJobGet(w http.ResponseWriter, r *http.Request, u database.User) {
+	jobID, err := uuid.Parse(chi.URLParam(r, "job_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid job_id")
+		return
+	}
+
+	job, err := cfg.DB.GetJobByID(r.Context(), database.GetJobByIDParams{ID: jobID, UserID: u.ID})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Job does not exist")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+// runRefreshJob is the jobs.Handler for jobs.KindRefresh.
+func (cfg *apiConfig) runRefreshJob(ctx context.Context, job database.Job) (int, error) {
+	feed, err := cfg.DB.GetFeedByID(ctx, job.FeedID)
+	if err != nil {
+		return 0, errors.Wrap(err, "getting feed")
+	}
+
+	result, err := scrapper.ScrapeFeed(ctx, cfg.DB, cfg.Events, feed)
+	if err != nil {
+		return result.NewPosts, err
+	}
+	cfg.ensureWebSub(ctx, feed, result)
+	return result.NewPosts, nil
+}
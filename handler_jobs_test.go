@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/auth"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+)
+
+func TestFeedRefreshPostConcurrentSameFeed(t *testing.T) {
+	cfg := newTestConfig(t)
+	router := v1Router(cfg)
+	ctx := context.Background()
+
+	owner := createTestUser(t, cfg, "owner")
+	follower := createTestUser(t, cfg, "follower")
+	feed := createTestFeed(t, cfg, owner, "https://example.com/feed.xml")
+	followTestFeed(t, cfg, follower, feed)
+
+	// both followers ask at once, so the per-user lock alone cannot keep one refresh
+	var keys []string
+	for _, user := range []database.User{owner, follower} {
+		_, key, err := cfg.createApiKey(ctx, user.ID, "test", []string{auth.ScopeFeedsWrite}, sql.NullTime{})
+		if err != nil {
+			t.Fatalf("creating API key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+
+	i := 0
+	codes := raceRequests(t, router, concurrentRequests, func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/feeds/"+feed.ID.String()+"/refresh", nil)
+		req.Header.Set("Authorization", "Bearer "+keys[i%len(keys)])
+		i++
+		return req
+	})
+	if codes[http.StatusAccepted] != 1 || codes[http.StatusTooManyRequests] != concurrentRequests-1 {
+		t.Fatalf("expected 1 x 202 and %d x 429, got %v", concurrentRequests-1, codes)
+	}
+}
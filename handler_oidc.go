@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/auth"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	oidcLoginCookieName = "oidc_login"
+	// time the user has to log in at the provider
+	oidcLoginTTL = 10 * time.Minute
+)
+
+// handlerOidcLogin sends the browser to the identity provider. The state, nonce
+// and PKCE verifier are kept in a cookie until the provider sends it back.
+func (cfg *apiConfig) handlerOidcLogin(w http.ResponseWriter, r *http.Request) {
+	if cfg.OIDC == nil {
+		respondWithError(w, http.StatusNotFound, "Single sign-on is not configured")
+		return
+	}
+
+	login, err := auth.NewOIDCLogin()
+	if err != nil {
+		cfg.Logger.Printf("Failed to start OIDC login: %+v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start login")
+		return
+	}
+	value, err := json.Marshal(login)
+	if err != nil {
+		cfg.Logger.Printf("Failed to encode OIDC login: %+v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start login")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcLoginCookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(value),
+		Path:     "/v1/auth/oidc",
+		MaxAge:   int(oidcLoginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   cfg.SecureCookies,
+		// the provider redirects back with a top-level GET, which Lax allows
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, cfg.OIDC.AuthCodeURL(login), http.StatusFound)
+}
+
+// handlerOidcCallback completes the login, creating the user on their first login,
+// and starts a session.
+func (cfg *apiConfig) handlerOidcCallback(w http.ResponseWriter, r *http.Request) {
+	if cfg.OIDC == nil {
+		respondWithError(w, http.StatusNotFound, "Single sign-on is not configured")
+		return
+	}
+
+	login, ok := readOidcLogin(r)
+	// the login cookie is single use
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcLoginCookieName,
+		Value:    "",
+		Path:     "/v1/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   cfg.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		respondWithError(w, http.StatusUnauthorized, "Login failed at the identity provider: "+providerErr)
+		return
+	}
+	if !ok || query.Get("state") == "" || query.Get("state") != login.State {
+		respondWithError(w, http.StatusBadRequest, "Login expired or was not started here. Please try again")
+		return
+	}
+
+	claims, err := cfg.OIDC.Exchange(r.Context(), login, query.Get("code"))
+	if err != nil {
+		cfg.Logger.Printf("Failed to complete OIDC login: %+v", err)
+		respondWithError(w, http.StatusUnauthorized, "Login failed. Please try again")
+		return
+	}
+
+	user, err := cfg.oidcUser(r.Context(), claims)
+	if err != nil {
+		cfg.Logger.Printf("Failed to get user for OIDC subject %q of %s: %+v", claims.Subject, claims.Issuer, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+	if user.DisabledAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Account is disabled")
+		return
+	}
+
+	if cfg.OIDCLoginRedirect == "" {
+		cfg.startSession(w, r, user, http.StatusOK)
+		return
+	}
+	if _, err := cfg.createSession(w, r, user); err != nil {
+		cfg.Logger.Printf("Failed to create session for user %v: %+v", user.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+	// the webui picks the session up from the cookie
+	http.Redirect(w, r, cfg.OIDCLoginRedirect, http.StatusFound)
+}
+
+func readOidcLogin(r *http.Request) (auth.OIDCLogin, bool) {
+	cookie, err := r.Cookie(oidcLoginCookieName)
+	if err != nil {
+		return auth.OIDCLogin{}, false
+	}
+	value, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return auth.OIDCLogin{}, false
+	}
+	var login auth.OIDCLogin
+	if err := json.Unmarshal(value, &login); err != nil {
+		return auth.OIDCLogin{}, false
+	}
+	return login, true
+}
+
+// oidcUser returns the user linked to the identity in claims, creating both on
+// the first login.
+func (cfg *apiConfig) oidcUser(ctx context.Context, claims auth.OIDCClaims) (database.User, error) {
+	email := sql.NullString{String: claims.Email, Valid: claims.Email != ""}
+
+	identity, err := cfg.DB.GetUserIdentity(ctx, database.GetUserIdentityParams{
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+	})
+	if err == nil {
+		if err := cfg.DB.RecordUserIdentityLogin(ctx, database.RecordUserIdentityLoginParams{
+			Issuer:      claims.Issuer,
+			Subject:     claims.Subject,
+			Email:       email,
+			LastLoginAt: time.Now(),
+		}); err != nil {
+			cfg.Logger.Printf("Failed to record login of identity %v: %+v", identity.ID, err)
+		}
+		user, err := cfg.DB.GetUserByID(ctx, identity.UserID)
+		return user, errors.Wrap(err, "getting user")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, errors.Wrap(err, "getting identity")
+	}
+
+	// the user and its identity are created together, so a failed or concurrent
+	// first login leaves no user without an identity behind
+	var user database.User
+	err = cfg.withTx(ctx, func(q *database.Queries) error {
+		var err error
+		user, err = createUserWithFreeName(ctx, q, oidcUserName(claims))
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+			ID:          uuid.New(),
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+			UserID:      user.ID,
+			Issuer:      claims.Issuer,
+			Subject:     claims.Subject,
+			Email:       email,
+			LastLoginAt: time.Now(),
+		})
+		return err
+	})
+	if isUniqueViolation(err) {
+		// another first login of the same identity won
+		identity, err := cfg.DB.GetUserIdentity(ctx, database.GetUserIdentityParams{
+			Issuer:  claims.Issuer,
+			Subject: claims.Subject,
+		})
+		if err != nil {
+			return database.User{}, errors.Wrap(err, "getting identity")
+		}
+		user, err := cfg.DB.GetUserByID(ctx, identity.UserID)
+		return user, errors.Wrap(err, "getting user")
+	}
+	if err != nil {
+		return database.User{}, errors.Wrap(err, "creating user with identity")
+	}
+	cfg.Logger.Printf("Created user %v for OIDC subject %q of %s", user.ID, claims.Subject, claims.Issuer)
+	return user, nil
+}
+
+var userNameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// oidcUserName picks a user name from the claims, preferring the username the
+// provider suggests.
+func oidcUserName(claims auth.OIDCClaims) string {
+	localPart, _, _ := strings.Cut(claims.Email, "@")
+	for _, candidate := range []string{claims.PreferredUsername, localPart, claims.Name} {
+		name := strings.Trim(userNameInvalidChars.ReplaceAllString(candidate, "-"), "-")
+		if name != "" {
+			return name
+		}
+	}
+	return "user"
+}
+
+// createUserWithFreeName creates a user called name, or name with a random suffix
+// when it is taken.
+func createUserWithFreeName(ctx context.Context, q *database.Queries, name string) (database.User, error) {
+	candidate := name
+	for i := 0; i < 5; i++ {
+		user, err := q.CreateUserIfNameFree(ctx, database.CreateUserIfNameFreeParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Name:      candidate,
+		})
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return database.User{}, errors.Wrap(err, "creating user")
+		}
+
+		suffix, err := generateToken(2)
+		if err != nil {
+			return database.User{}, err
+		}
+		candidate = name + "-" + suffix
+	}
+	return database.User{}, errors.Errorf("no free user name for %q", name)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/auth"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestOidcUserConcurrentFirstLogin(t *testing.T) {
+	cfg := newTestConfig(t)
+	claims := auth.OIDCClaims{
+		Issuer:            "https://issuer.example.com",
+		Subject:           "subject-1",
+		PreferredUsername: "alice",
+	}
+
+	var (
+		wg    sync.WaitGroup
+		ids   = make([]uuid.UUID, concurrentRequests)
+		errs  = make([]error, concurrentRequests)
+		start = make(chan struct{})
+	)
+	for i := 0; i < concurrentRequests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			user, err := cfg.oidcUser(context.Background(), claims)
+			ids[i], errs[i] = user.ID, err
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("login %d: %v", i, err)
+		}
+		if ids[i] != ids[0] {
+			t.Errorf("login %d got user %v, want %v", i, ids[i], ids[0])
+		}
+	}
+
+	users, err := cfg.DB.GetUsers(context.Background(), database.GetUsersParams{RowLimit: 100})
+	if err != nil {
+		t.Fatalf("listing users: %v", err)
+	}
+	if len(users) != 1 {
+		t.Errorf("got %d users, want 1 without orphans", len(users))
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/jobs"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/opml"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	maxOpmlSize    = 1 << 20 // 1 MiB
+	maxOpmlEntries = 500
+)
+
+const (
+	opmlStatusCreated          = "created"
+	opmlStatusFollowed         = "followed"
+	opmlStatusAlreadyFollowing = "already_following"
+	opmlStatusDuplicate        = "duplicate"
+	opmlStatusInvalid          = "invalid"
+	opmlStatusFailed           = "failed"
+)
+
+type opmlImportResult struct {
+	URL    string     `json:"url"`
+	Title  string     `json:"title"`
+	Folder string     `json:"folder,omitempty"`
+	Status string     `json:"status"`
+	FeedID *uuid.UUID `json:"feed_id,omitempty"`
+	// JobID is the job validating a feed added by the import, see GET /v1/jobs/{job_id}
+	JobID *uuid.UUID `json:"job_id,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+// handlerOpmlPost imports the subscriptions of an OPML file, sent either as the
+// raw request body or as the "file" field of a multipart form. Feeds that are new
+// to the server are added as pending and validated by a create_feed job each,
+// like feeds added through POST /v1/feeds.
+func (cfg *apiConfig) handlerOpmlPost(w http.ResponseWriter, r *http.Request, u database.User) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxOpmlSize)
+	defer r.Body.Close()
+
+	var body io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Expected the OPML file in the 'file' form field")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	subs, err := opml.Parse(body)
+	if err != nil {
+		cfg.Logger.Printf("Failed to parse OPML: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid OPML file")
+		return
+	}
+	if len(subs) == 0 {
+		respondWithError(w, http.StatusBadRequest, "OPML file has no feeds")
+		return
+	}
+	if len(subs) > maxOpmlEntries {
+		respondWithError(w, http.StatusBadRequest, "OPML file has too many feeds")
+		return
+	}
+
+	results := make([]opmlImportResult, len(subs))
+	seen := make(map[string]bool)
+	folders := make(map[string]database.Folder)
+
+	for i, sub := range subs {
+		results[i] = opmlImportResult{URL: sub.XMLURL, Title: sub.Title, Folder: sub.Folder}
+		if seen[sub.XMLURL] {
+			results[i].Status = opmlStatusDuplicate
+			continue
+		}
+		seen[sub.XMLURL] = true
+
+		cfg.importSubscription(r.Context(), u, sub, folders, &results[i])
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{"results": results})
+}
+
+// importSubscription follows the feed of sub for u, adding it first if needed,
+// and records the outcome in result.
+func (cfg *apiConfig) importSubscription(ctx context.Context, u database.User, sub opml.Subscription, folders map[string]database.Folder, result *opmlImportResult) {
+	if parsedURL, err := url.ParseRequestURI(sub.XMLURL); err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		result.Status = opmlStatusInvalid
+		result.Error = "xmlUrl must be an absolute http or https URL"
+		return
+	}
+
+	feed, created, err := cfg.pendingFeedForURL(ctx, u, sub)
+	if err != nil {
+		cfg.Logger.Printf("Failed to create feed %v: %+v", sub.XMLURL, err)
+		result.Status = opmlStatusFailed
+		result.Error = "Failed to create feed"
+		return
+	}
+	result.FeedID = &feed.ID
+
+	if created {
+		job, err := cfg.Jobs.Enqueue(ctx, jobs.KindCreateFeed, u.ID, feed.ID)
+		if err != nil {
+			cfg.Logger.Printf("Failed to queue fetch of new feed %v: %+v", feed.ID, err)
+			result.Status = opmlStatusFailed
+			result.Error = "Failed to create feed"
+			return
+		}
+		result.JobID = &job.ID
+	}
+
+	if _, err := cfg.DB.GetFeedFollows(ctx, database.GetFeedFollowsParams{FeedID: feed.ID, UserID: u.ID}); err == nil {
+		result.Status = opmlStatusAlreadyFollowing
+		return
+	}
+
+	var folderID uuid.NullUUID
+	if sub.Folder != "" {
+		folder, err := cfg.folderByName(ctx, folders, u.ID, sub.Folder)
+		if err != nil {
+			cfg.Logger.Printf("Failed to get folder %q: %+v", sub.Folder, err)
+			result.Status = opmlStatusFailed
+			result.Error = "Failed to create folder"
+			return
+		}
+		folderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+	}
+
+	if _, err := cfg.DB.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		FeedID:    feed.ID,
+		UserID:    u.ID,
+		FolderID:  folderID,
+	}); isUniqueViolation(err) {
+		// followed concurrently
+		result.Status = opmlStatusAlreadyFollowing
+		return
+	} else if err != nil {
+		cfg.Logger.Printf("Failed to create feed follow: %+v", err)
+		result.Status = opmlStatusFailed
+		result.Error = "Failed to create feed follow"
+		return
+	}
+
+	result.Status = opmlStatusFollowed
+	if created {
+		result.Status = opmlStatusCreated
+	}
+}
+
+// pendingFeedForURL returns the feed of sub. A feed new to the server, or one whose
+// earlier validation failed, is returned as pending with created set, and needs a
+// create_feed job.
+func (cfg *apiConfig) pendingFeedForURL(ctx context.Context, u database.User, sub opml.Subscription) (database.Feed, bool, error) {
+	feed, err := cfg.DB.GetFeedByURL(ctx, sub.XMLURL)
+	if err == nil {
+		if feed.Status != feedStatusFailed {
+			return feed, false, nil
+		}
+		// importing a failed feed again tries it once more
+		feed, err = cfg.DB.SetFeedStatus(ctx, database.SetFeedStatusParams{
+			ID:        feed.ID,
+			Status:    feedStatusPending,
+			UpdatedAt: time.Now(),
+		})
+		return feed, err == nil, err
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, false, err
+	}
+
+	// replaced by the channel title once the feed is fetched
+	title := sub.Title
+	if title == "" {
+		title = sub.XMLURL
+	}
+	feed, err = cfg.DB.CreateFeed(ctx, database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    u.ID,
+		Url:       sub.XMLURL,
+		Title:     title,
+		Status:    feedStatusPending,
+	})
+	if isUniqueViolation(err) {
+		// added concurrently by someone else, their job validates it
+		feed, err = cfg.DB.GetFeedByURL(ctx, sub.XMLURL)
+		return feed, false, err
+	}
+	return feed, err == nil, err
+}
+
+// folderByName returns the user's folder with the given name, creating it if needed.
+func (cfg *apiConfig) folderByName(ctx context.Context, cache map[string]database.Folder, userID uuid.UUID, name string) (database.Folder, error) {
+	if folder, ok := cache[name]; ok {
+		return folder, nil
+	}
+
+	folder, err := cfg.DB.GetFolderByName(ctx, database.GetFolderByNameParams{UserID: userID, Name: name})
+	if err != nil {
+		folder, err = cfg.DB.CreateFolder(ctx, database.CreateFolderParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    userID,
+			Name:      name,
+		})
+		if err != nil {
+			return database.Folder{}, err
+		}
+	}
+
+	cache[name] = folder
+	return folder, nil
+}
+
+// handlerOpmlGet exports the caller's followed feeds as an OPML 2.0 file.
+func (cfg *apiConfig) handlerOpmlGet(w http.ResponseWriter, r *http.Request, u database.User) {
+	body, err := cfg.renderOpml(r.Context(), u)
+	if err != nil {
+		cfg.Logger.Printf("Failed to render OPML: %+v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to render OPML")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="subscriptions.opml"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		cfg.Logger.Printf("Failed to write OPML response: %+v", err)
+	}
+}
+
+// renderOpml returns the subscriptions of u as an OPML document.
+func (cfg *apiConfig) renderOpml(ctx context.Context, u database.User) ([]byte, error) {
+	feedFollows, err := cfg.DB.GetFeedFollowsWithFolderByUser(ctx, u.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting feed_follows")
+	}
+
+	subs := make([]opml.Subscription, 0, len(feedFollows))
+	for _, ff := range feedFollows {
+		subs = append(subs, opml.Subscription{
+			Title:   ff.Title,
+			XMLURL:  ff.Url,
+			HTMLURL: ff.Link,
+			Folder:  ff.FolderName.String,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := opml.Render(&buf, u.Name+"'s subscriptions", subs); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
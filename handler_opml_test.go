@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/auth"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+)
+
+func TestOpmlPostQueuesNewFeedsAndSetsFolders(t *testing.T) {
+	cfg := newTestConfig(t)
+	router := v1Router(cfg)
+	ctx := context.Background()
+
+	owner := createTestUser(t, cfg, "owner")
+	known := createTestFeed(t, cfg, owner, "https://example.com/known.xml")
+	importer := createTestUser(t, cfg, "importer")
+	_, key, err := cfg.createApiKey(ctx, importer.ID, "test", []string{auth.ScopeFeedsWrite, auth.ScopeFollowsWrite}, sql.NullTime{})
+	if err != nil {
+		t.Fatalf("creating API key: %v", err)
+	}
+
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0"><body>
+  <outline text="Tech">
+    <outline text="Known" type="rss" xmlUrl="https://example.com/known.xml"/>
+    <outline text="New" type="rss" xmlUrl="https://example.com/new.xml"/>
+  </outline>
+  <outline text="Broken" type="rss" xmlUrl="not a url"/>
+</body></opml>`
+	req := httptest.NewRequest(http.MethodPost, "/opml", strings.NewReader(doc))
+	req.Header.Set("Authorization", "Bearer "+key)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("importing OPML: got %d %s", rec.Code, rec.Body)
+	}
+
+	var resp struct {
+		Results []opmlImportResult `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	results := make(map[string]opmlImportResult)
+	for _, result := range resp.Results {
+		results[result.URL] = result
+	}
+
+	if got := results[known.Url]; got.Status != opmlStatusFollowed || got.JobID != nil {
+		t.Errorf("known feed: got %+v, want followed without a job", got)
+	}
+	newFeed := results["https://example.com/new.xml"]
+	if newFeed.Status != opmlStatusCreated || newFeed.JobID == nil || newFeed.FeedID == nil {
+		t.Fatalf("new feed: got %+v, want created with a job", newFeed)
+	}
+	if got := results["not a url"]; got.Status != opmlStatusInvalid {
+		t.Errorf("invalid url: got %+v, want invalid", got)
+	}
+
+	feed, err := cfg.DB.GetFeedByID(ctx, *newFeed.FeedID)
+	if err != nil {
+		t.Fatalf("getting new feed: %v", err)
+	}
+	if feed.Status != feedStatusPending {
+		t.Errorf("new feed status is %v, want pending until its job runs", feed.Status)
+	}
+
+	for _, f := range []database.Feed{known, feed} {
+		ff, err := cfg.DB.GetFeedFollows(ctx, database.GetFeedFollowsParams{FeedID: f.ID, UserID: importer.ID})
+		if err != nil {
+			t.Fatalf("getting follow of %v: %v", f.Url, err)
+		}
+		if !ff.FolderID.Valid {
+			t.Errorf("follow of %v has no folder", f.Url)
+		}
+	}
+}
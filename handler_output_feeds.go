@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/feedgen"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/search"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const outputFeedSize = 50
+
+type outputFeedResponse struct {
+	database.OutputFeed
+	RSSURL  string `json:"rss_url"`
+	AtomURL string `json:"atom_url"`
+}
+
+func (cfg *apiConfig) newOutputFeedResponse(of database.OutputFeed) outputFeedResponse {
+	base := cfg.PublicURL + "/v1/output_feeds/" + of.Token
+	return outputFeedResponse{
+		OutputFeed: of,
+		RSSURL:     base + "/rss",
+		AtomURL:    base + "/atom",
+	}
+}
+
+// outputFeedsEnabled responds with an error when PUBLIC_URL is not configured.
+// Output feed URLs carry their secret token, so they are never built from the
+// Host and X-Forwarded-Proto headers a client controls.
+func (cfg *apiConfig) outputFeedsEnabled(w http.ResponseWriter) bool {
+	if cfg.PublicURL == "" {
+		respondWithError(w, http.StatusServiceUnavailable, "Output feeds need PUBLIC_URL to be configured")
+		return false
+	}
+	return true
+}
+
+// generateToken returns a random hex token with n bytes of entropy.
+func generateToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generating token")
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// handlerOutputFeedsPost creates an output feed of the user's timeline, narrowed
+// to a folder and a search query. The filters are stored with the feed rather
+// than encoded in its URL, so whoever has the URL cannot widen them to the rest
+// of the timeline.
+func (cfg *apiConfig) handlerOutputFeedsPost(w http.ResponseWriter, r *http.Request, u database.User) {
+	if !cfg.outputFeedsEnabled(w) {
+		return
+	}
+
+	var of struct {
+		Name     string        `json:"name"`
+		FolderID uuid.NullUUID `json:"folder_id"`
+		Q        string        `json:"q"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&of); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	name := strings.TrimSpace(of.Name)
+	if name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	if of.FolderID.Valid {
+		if _, err := cfg.DB.GetFolderByID(r.Context(), database.GetFolderByIDParams{ID: of.FolderID.UUID, UserID: u.ID}); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Folder does not exist")
+			return
+		}
+	}
+
+	var query sql.NullString
+	if q := strings.TrimSpace(of.Q); q != "" {
+		if _, err := search.ParseQuery(q); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid q: "+err.Error())
+			return
+		}
+		query = sql.NullString{String: q, Valid: true}
+	}
+
+	token, err := generateToken(32)
+	if err != nil {
+		cfg.Logger.Printf("Failed to generate output feed token: %+v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create output feed")
+		return
+	}
+
+	outputFeed, err := cfg.DB.CreateOutputFeed(r.Context(), database.CreateOutputFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    u.ID,
+		Name:      name,
+		Token:     token,
+		FolderID:  of.FolderID,
+		Query:     query,
+	})
+	if err != nil {
+		cfg.Logger.Printf("Failed to create output feed: %+v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create output feed")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, cfg.newOutputFeedResponse(outputFeed))
+}
+
+func (cfg *apiConfig) handlerOutputFeedsGet(w http.ResponseWriter, r *http.Request, u database.User) {
+	if !cfg.outputFeedsEnabled(w) {
+		return
+	}
+
+	outputFeeds, err := cfg.DB.GetOutputFeedsByUser(r.Context(), u.ID)
+	if err != nil {
+		cfg.Logger.Printf("Failed to get output feeds for user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get output feeds")
+		return
+	}
+
+	result := make([]outputFeedResponse, 0, len(outputFeeds))
+	for _, of := range outputFeeds {
+		result = append(result, cfg.newOutputFeedResponse(of))
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+func (cfg *apiConfig) handlerOutputFeedsDelete(w http.ResponseWriter, r *http.Request, u database.User) {
+	outputFeedID, err := uuid.Parse(chi.URLParam(r, "output_feed_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid output_feed_id")
+		return
+	}
+
+	deleted, err := cfg.DB.DeleteOutputFeed(r.Context(), database.DeleteOutputFeedParams{ID: outputFeedID, UserID: u.ID})
+	if err != nil {
+		cfg.Logger.Printf("Failed to delete output feed %v: %+v", outputFeedID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete output feed")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Output feed does not exist")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handlerOutputFeedRender serves an output feed as RSS or Atom. It is public:
+// the token in the URL is the only credential.
+func (cfg *apiConfig) handlerOutputFeedRender(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.outputFeedsEnabled(w) {
+			return
+		}
+
+		token := chi.URLParam(r, "token")
+		outputFeed, err := cfg.DB.GetOutputFeedByToken(r.Context(), token)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Output feed does not exist")
+			return
+		}
+
+		user, err := cfg.DB.GetUserByID(r.Context(), outputFeed.UserID)
+		if err != nil {
+			cfg.Logger.Printf("Failed to get user %v for output feed: %+v", outputFeed.UserID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to render output feed")
+			return
+		}
+		// feeds of disabled accounts stop being published
+		if user.DisabledAt.Valid {
+			respondWithError(w, http.StatusNotFound, "Output feed does not exist")
+			return
+		}
+
+		var query sql.NullString
+		if outputFeed.Query.Valid {
+			tsQuery, err := search.ParseQuery(outputFeed.Query.String)
+			if err != nil {
+				cfg.Logger.Printf("Failed to parse output feed query %q: %+v", outputFeed.Query.String, err)
+				respondWithError(w, http.StatusInternalServerError, "Failed to render output feed")
+				return
+			}
+			query = sql.NullString{String: tsQuery, Valid: true}
+		}
+
+		posts, err := cfg.DB.GetFilteredPostsByUser(r.Context(), database.GetFilteredPostsByUserParams{
+			UserID:    outputFeed.UserID,
+			FolderID:  outputFeed.FolderID,
+			FeedIds:   []uuid.UUID{},
+			Query:     query,
+			PageLimit: outputFeedSize,
+		})
+		if err != nil {
+			cfg.Logger.Printf("Failed to get posts for output feed %v: %+v", outputFeed.ID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to render output feed")
+			return
+		}
+
+		selfLink := cfg.PublicURL + "/v1/output_feeds/" + outputFeed.Token + "/" + format
+		feed := feedgen.Feed{
+			Title:       outputFeed.Name,
+			Link:        cfg.PublicURL,
+			SelfLink:    selfLink,
+			Description: "Posts from the feeds " + user.Name + " follows",
+			Updated:     outputFeed.UpdatedAt,
+		}
+		for _, post := range posts {
+			feed.Items = append(feed.Items, feedgen.Item{
+				ID:          "urn:uuid:" + post.ID.String(),
+				Title:       post.Title,
+				Link:        post.Url,
+				Description: post.Description,
+				Published:   post.PublishDate,
+			})
+			if post.CreatedAt.After(feed.Updated) {
+				feed.Updated = post.CreatedAt
+			}
+		}
+
+		var buf bytes.Buffer
+		contentType := "application/rss+xml; charset=utf-8"
+		if format == "atom" {
+			contentType = "application/atom+xml; charset=utf-8"
+			err = feedgen.RenderAtom(&buf, feed)
+		} else {
+			err = feedgen.RenderRSS(&buf, feed)
+		}
+		if err != nil {
+			cfg.Logger.Printf("Failed to render output feed %v: %+v", outputFeed.ID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to render output feed")
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			cfg.Logger.Printf("Failed to write output feed response: %+v", err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/search"
+)
+
+func (cfg *apiConfig) handlerSearchGet(w http.ResponseWriter, r *http.Request, u database.User) {
+	queries := r.URL.Query()
+	q := queries.Get("q")
+	if q == "" {
+		respondWithError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	tsQuery, err := search.ParseQuery(q)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid search query: "+err.Error())
+		return
+	}
+
+	// results are ranked, so they are paged by offset rather than by cursor
+	page, err := parsePageRequest(queries)
+	if err != nil || page.Cursor != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid offset or limit")
+		return
+	}
+
+	results, err := cfg.DB.SearchPostsByUser(r.Context(), database.SearchPostsByUserParams{
+		Query:     tsQuery,
+		UserID:    u.ID,
+		RowOffset: page.Offset,
+		RowLimit:  page.Limit,
+	})
+	if err != nil {
+		cfg.Logger.Printf("Failed to search posts for user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to search posts")
+		return
+	}
+	if results == nil {
+		results = []database.SearchPostsByUserRow{}
+	}
+	// snippet is HTML: escaped text with the matches wrapped in <mark>
+	for i := range results {
+		results[i].Snippet = search.Snippet(results[i].Snippet)
+	}
+
+	respondWithJSON(w, http.StatusOK, results)
+}
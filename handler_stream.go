@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/events"
+	"github.com/google/uuid"
+)
+
+const (
+	streamHeartbeat   = 15 * time.Second
+	streamReplayLimit = 500
+	streamRetry       = 5 * time.Second
+)
+
+// handlerStreamGet pushes new posts from the user's followed feeds as Server-Sent
+// Events. Each event id is an ingestion-order cursor, so a client reconnecting
+// with Last-Event-ID receives the posts it missed before live events resume.
+// Live posts are read from the database after the last sent one, not taken from
+// the event, so posts whose event the bus dropped are sent with the next event
+// or heartbeat. When more than streamReplayLimit posts are missing the client
+// gets a "resync" event instead and should reload the timeline through GET /v1/posts.
+func (cfg *apiConfig) handlerStreamGet(w http.ResponseWriter, r *http.Request, u database.User) {
+	// without Last-Event-ID the stream starts with posts created from now on
+	cursor := postCursor{Sort: sortIngested, Key: time.Now()}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	if lastEventID != "" {
+		var err error
+		cursor, err = decodePostCursor(lastEventID)
+		if err != nil || cursor.Sort != sortIngested {
+			respondWithError(w, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	// a stream lives far longer than the server wide write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		cfg.Logger.Printf("Failed to clear write deadline for stream: %+v", err)
+		respondWithError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	// subscribe before replaying so posts created in between are not lost
+	ch, unsubscribe := cfg.Events.Subscribe()
+	defer unsubscribe()
+
+	followed, err := cfg.streamFeeds(r, u)
+	if err != nil {
+		cfg.Logger.Printf("Failed to get followed feeds of user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start stream")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds()); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	// catchUp sends the posts created after cursor and moves cursor past them
+	catchUp := func() error {
+		posts, err := cfg.DB.GetPostsByUserCreatedAfter(r.Context(), database.GetPostsByUserCreatedAfterParams{
+			UserID:    u.ID,
+			AfterDate: cursor.Key,
+			AfterID:   cursor.ID,
+			PageLimit: streamReplayLimit + 1,
+		})
+		if err != nil {
+			cfg.Logger.Printf("Failed to get new posts for user %v: %+v", u.ID, err)
+			return err
+		}
+
+		if len(posts) > streamReplayLimit {
+			// too much was missed to send, the client reloads the timeline instead
+			cursor = postCursor{Sort: sortIngested, Key: time.Now()}
+			if _, err := fmt.Fprintf(w, "event: resync\ndata: {\"reason\":\"too_many_missed_posts\",\"limit\":%d}\n\n", streamReplayLimit); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+		for _, post := range posts {
+			data, err := json.Marshal(post)
+			if err != nil {
+				return err
+			}
+			cursor = newPostCursor(sortIngested, post)
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: post\ndata: %s\n\n", cursor.encode(), data); err != nil {
+				return err
+			}
+		}
+		return rc.Flush()
+	}
+
+	if lastEventID != "" {
+		if err := catchUp(); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			// picks up follows changed since the stream started and posts whose
+			// event was dropped without a later event to notice the gap
+			if feeds, err := cfg.streamFeeds(r, u); err == nil {
+				followed = feeds
+			}
+			if err := catchUp(); err != nil {
+				return
+			}
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			// events of other feeds never reach the database
+			if e.Type != events.PostCreated || !followed[e.FeedID] {
+				continue
+			}
+			if err := catchUp(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// streamFeeds returns the feeds whose posts the stream of u shows.
+func (cfg *apiConfig) streamFeeds(r *http.Request, u database.User) (map[uuid.UUID]bool, error) {
+	follows, err := cfg.DB.GetFeedFollowsByUser(r.Context(), u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	feeds := make(map[uuid.UUID]bool, len(follows))
+	for _, follow := range follows {
+		if !follow.Hidden {
+			feeds[follow.ID] = true
+		}
+	}
+	return feeds, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/pkg/errors"
+)
+
+// handlerUsersPatch renames the authenticated user.
+func (cfg *apiConfig) handlerUsersPatch(w http.ResponseWriter, r *http.Request, u database.User) {
+	var p struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	name := strings.TrimSpace(p.Name)
+	if name == "" {
+		respondWithError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+	if name == u.Name {
+		respondWithJSON(w, http.StatusOK, u)
+		return
+	}
+
+	user, err := cfg.DB.UpdateUser(r.Context(), database.UpdateUserParams{
+		ID:        u.ID,
+		UpdatedAt: time.Now(),
+		Name:      name,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Name is already taken")
+		return
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to update user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// accountExport is what a user takes with them when deleting their account.
+type accountExport struct {
+	User        database.User                                `json:"user"`
+	Folders     []database.Folder                            `json:"folders"`
+	FeedFollows []database.GetFeedFollowsWithFolderByUserRow `json:"feed_follows"`
+	// Opml holds the subscriptions in a format other feed readers import
+	Opml string `json:"opml"`
+}
+
+// handlerUsersDelete deletes the authenticated user and everything they own. The
+// body must confirm the deletion by repeating the user's name. With ?export=true
+// the response carries an export of the account's subscriptions. Feeds the user
+// added that others follow are handed over to their oldest follower instead of
+// being deleted with the account.
+func (cfg *apiConfig) handlerUsersDelete(w http.ResponseWriter, r *http.Request, u database.User) {
+	var p struct {
+		Confirm string `json:"confirm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	if p.Confirm != u.Name {
+		respondWithError(w, http.StatusBadRequest, "To delete your account, set confirm to your user name")
+		return
+	}
+
+	var export *accountExport
+	if r.URL.Query().Get("export") == "true" {
+		e, err := cfg.exportAccount(r.Context(), u)
+		if err != nil {
+			// nothing is deleted without the export that was asked for
+			cfg.Logger.Printf("Failed to export user %v: %+v", u.ID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to export account, it was not deleted")
+			return
+		}
+		export = &e
+	}
+
+	err := cfg.withTx(r.Context(), func(q *database.Queries) error {
+		feeds, err := q.GetFeedsByOwner(r.Context(), u.ID)
+		if err != nil {
+			return errors.Wrap(err, "getting owned feeds")
+		}
+		// feeds nobody else follows are deleted with the user
+		for _, feed := range feeds {
+			if _, _, err := handOverFeed(r.Context(), q, feed); err != nil {
+				return errors.Wrapf(err, "handing over feed %v", feed.ID)
+			}
+		}
+
+		_, err = q.DeleteUser(r.Context(), u.ID)
+		return errors.Wrap(err, "deleting user")
+	})
+	if err != nil {
+		cfg.Logger.Printf("Failed to delete user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete account")
+		return
+	}
+	cfg.Logger.Printf("User %v deleted their account", u.ID)
+
+	// the session went with the user
+	cfg.clearSessionCookie(w)
+	respondWithJSON(w, http.StatusOK, struct {
+		Status string         `json:"status"`
+		Export *accountExport `json:"export,omitempty"`
+	}{
+		Status: "ok",
+		Export: export,
+	})
+}
+
+func (cfg *apiConfig) exportAccount(ctx context.Context, u database.User) (accountExport, error) {
+	folders, err := cfg.DB.GetFoldersByUser(ctx, u.ID)
+	if err != nil {
+		return accountExport{}, errors.Wrap(err, "getting folders")
+	}
+	feedFollows, err := cfg.DB.GetFeedFollowsWithFolderByUser(ctx, u.ID)
+	if err != nil {
+		return accountExport{}, errors.Wrap(err, "getting feed_follows")
+	}
+	subscriptions, err := cfg.renderOpml(ctx, u)
+	if err != nil {
+		return accountExport{}, errors.Wrap(err, "rendering OPML")
+	}
+
+	if folders == nil {
+		folders = []database.Folder{}
+	}
+	if feedFollows == nil {
+		feedFollows = []database.GetFeedFollowsWithFolderByUserRow{}
+	}
+	return accountExport{
+		User:        u,
+		Folders:     folders,
+		FeedFollows: feedFollows,
+		Opml:        string(subscriptions),
+	}, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/auth"
+	"github.com/pkg/errors"
+)
+
+func TestUsersDeleteHandsOverFollowedFeeds(t *testing.T) {
+	cfg := newTestConfig(t)
+	router := v1Router(cfg)
+	ctx := context.Background()
+
+	owner := createTestUser(t, cfg, "owner")
+	follower := createTestUser(t, cfg, "follower")
+	shared := createTestFeed(t, cfg, owner, "https://example.com/shared.xml")
+	followTestFeed(t, cfg, follower, shared)
+	private := createTestFeed(t, cfg, owner, "https://example.com/private.xml")
+
+	_, key, err := cfg.createApiKey(ctx, owner.ID, "test", []string{auth.ScopeAdmin}, sql.NullTime{})
+	if err != nil {
+		t.Fatalf("creating API key: %v", err)
+	}
+
+	body, _ := json.Marshal(map[string]string{"confirm": owner.Name})
+	req := httptest.NewRequest(http.MethodDelete, "/users", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+key)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("deleting account: got %d %s", rec.Code, rec.Body)
+	}
+
+	feed, err := cfg.DB.GetFeedByID(ctx, shared.ID)
+	if err != nil {
+		t.Fatalf("followed feed was deleted with its owner: %v", err)
+	}
+	if feed.UserID != follower.ID {
+		t.Errorf("followed feed is owned by %v, want the follower %v", feed.UserID, follower.ID)
+	}
+	if _, err := cfg.DB.GetFeedByID(ctx, private.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("feed nobody else follows should be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/webhook"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultDeliveryLogSize = 50
+	maxDeliveryLogSize     = 200
+	webhookTestDeadline    = 30 * time.Second
+)
+
+// webhookResponse only carries the secret when the webhook is created.
+type webhookResponse struct {
+	database.Webhook
+	Secret string `json:"secret,omitempty"`
+}
+
+func (cfg *apiConfig) handlerWebhooksPost(w http.ResponseWriter, r *http.Request, u database.User) {
+	var wh struct {
+		URL     string        `json:"url"`
+		Secret  string        `json:"secret"`
+		FeedID  uuid.NullUUID `json:"feed_id"`
+		Keyword string        `json:"keyword"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&wh); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	target, err := url.Parse(strings.TrimSpace(wh.URL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		respondWithError(w, http.StatusBadRequest, "url must be an absolute http or https URL")
+		return
+	}
+	if err := webhook.CheckHost(r.Context(), target.Hostname()); errors.Is(err, webhook.ErrPrivateAddress) {
+		respondWithError(w, http.StatusBadRequest, "url must point at a public address")
+		return
+	} else if err != nil {
+		cfg.Logger.Printf("Failed to resolve webhook url %v: %+v", target, err)
+		respondWithError(w, http.StatusBadRequest, "Failed to resolve the url host")
+		return
+	}
+
+	if wh.FeedID.Valid {
+		if _, err := cfg.DB.GetFeedByID(r.Context(), wh.FeedID.UUID); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Feed does not exist")
+			return
+		}
+	}
+
+	var keyword sql.NullString
+	if k := strings.TrimSpace(wh.Keyword); k != "" {
+		keyword = sql.NullString{String: k, Valid: true}
+	}
+
+	secret := wh.Secret
+	if secret == "" {
+		secret, err = generateToken(32)
+		if err != nil {
+			cfg.Logger.Printf("Failed to generate webhook secret: %+v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to create webhook")
+			return
+		}
+	}
+
+	hook, err := cfg.DB.CreateWebhook(r.Context(), database.CreateWebhookParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    u.ID,
+		Url:       target.String(),
+		Secret:    secret,
+		FeedID:    wh.FeedID,
+		Keyword:   keyword,
+	})
+	if err != nil {
+		cfg.Logger.Printf("Failed to create webhook: %+v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create webhook")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, webhookResponse{Webhook: hook, Secret: hook.Secret})
+}
+
+func (cfg *apiConfig) handlerWebhooksGet(w http.ResponseWriter, r *http.Request, u database.User) {
+	webhooks, err := cfg.DB.GetWebhooksByUser(r.Context(), u.ID)
+	if err != nil {
+		cfg.Logger.Printf("Failed to get webhooks for user %v: %+v", u.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get webhooks")
+		return
+	}
+
+	if webhooks == nil {
+		webhooks = []database.Webhook{}
+	}
+	respondWithJSON(w, http.StatusOK, webhooks)
+}
+
+func (cfg *apiConfig) handlerWebhooksDelete(w http.ResponseWriter, r *http.Request, u database.User) {
+	webhookID, err := uuid.Parse(chi.URLParam(r, "webhook_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook_id")
+		return
+	}
+
+	deleted, err := cfg.DB.DeleteWebhook(r.Context(), database.DeleteWebhookParams{ID: webhookID, UserID: u.ID})
+	if err != nil {
+		cfg.Logger.Printf("Failed to delete webhook %v: %+v", webhookID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete webhook")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Webhook does not exist")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// webhookForUser loads the webhook named in the URL, responding with an error if
+// it is not the caller's.
+func (cfg *apiConfig) webhookForUser(w http.ResponseWriter, r *http.Request, u database.User) (database.Webhook, bool) {
+	webhookID, err := uuid.Parse(chi.URLParam(r, "webhook_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook_id")
+		return database.Webhook{}, false
+	}
+
+	hook, err := cfg.DB.GetWebhookByID(r.Context(), database.GetWebhookByIDParams{ID: webhookID, UserID: u.ID})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Webhook does not exist")
+		return database.Webhook{}, false
+	}
+	return hook, true
+}
+
+// handlerWebhookDeliveriesGet returns the most recent deliveries of a webhook, newest first.
+func (cfg *apiConfig) handlerWebhookDeliveriesGet(w http.ResponseWriter, r *http.Request, u database.User) {
+	hook, ok := cfg.webhookForUser(w, r, u)
+	if !ok {
+		return
+	}
+
+	limit := defaultDeliveryLogSize
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxDeliveryLogSize {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := cfg.DB.GetWebhookDeliveries(r.Context(), database.GetWebhookDeliveriesParams{
+		WebhookID: hook.ID,
+		Limit:     int32(limit),
+	})
+	if err != nil {
+		cfg.Logger.Printf("Failed to get deliveries for webhook %v: %+v", hook.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get webhook deliveries")
+		return
+	}
+
+	if deliveries == nil {
+		deliveries = []database.WebhookDelivery{}
+	}
+	respondWithJSON(w, http.StatusOK, deliveries)
+}
+
+// handlerWebhookTestPost sends a test payload right away and returns the recorded delivery.
+func (cfg *apiConfig) handlerWebhookTestPost(w http.ResponseWriter, r *http.Request, u database.User) {
+	hook, ok := cfg.webhookForUser(w, r, u)
+	if !ok {
+		return
+	}
+
+	// the receiver may take up to the delivery timeout, which is the server wide write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(webhookTestDeadline)); err != nil {
+		cfg.Logger.Printf("Failed to extend write deadline: %+v", err)
+	}
+
+	delivery, err := cfg.Webhooks.SendTest(r.Context(), hook)
+	if err != nil {
+		cfg.Logger.Printf("Failed to send test delivery for webhook %v: %+v", hook.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to send test delivery")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, delivery)
+}
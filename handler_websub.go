@@ -0,0 +1,144 @@
+package main
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/scrapper"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/websub"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const maxWebsubContentSize = 5 << 20 // 5 MiB
+
+// websubSubscription loads the subscription of the feed in the URL. Hubs are told
+// the subscription is gone when there is none, so they stop calling.
+func (cfg *apiConfig) websubSubscription(w http.ResponseWriter, r *http.Request) (database.WebsubSubscription, bool) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "feed_id"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Unknown subscription")
+		return database.WebsubSubscription{}, false
+	}
+
+	sub, err := cfg.DB.GetWebsubSubscription(r.Context(), feedID)
+	if err != nil {
+		respondWithError(w, http.StatusGone, "Unknown subscription")
+		return database.WebsubSubscription{}, false
+	}
+	return sub, true
+}
+
+// handlerWebsubVerify answers the hub's verification of intent, or records a
+// denied subscription.
+func (cfg *apiConfig) handlerWebsubVerify(w http.ResponseWriter, r *http.Request) {
+	sub, ok := cfg.websubSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("hub.topic") != sub.TopicUrl {
+		respondWithError(w, http.StatusNotFound, "Unknown topic")
+		return
+	}
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		// only a subscription we have asked for or hold is confirmed
+		if sub.Status != websub.StatusPending && sub.Status != websub.StatusActive {
+			respondWithError(w, http.StatusNotFound, "Subscription was not requested")
+			return
+		}
+		leaseSeconds, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err != nil || leaseSeconds <= 0 {
+			leaseSeconds = websub.LeaseSeconds
+		}
+		activated, err := cfg.DB.ActivateWebsubSubscription(r.Context(), database.ActivateWebsubSubscriptionParams{
+			FeedID:         sub.FeedID,
+			LeaseExpiresAt: sql.NullTime{Time: time.Now().Add(time.Duration(leaseSeconds) * time.Second), Valid: true},
+			UpdatedAt:      time.Now(),
+		})
+		if err != nil {
+			cfg.Logger.Printf("Failed to activate WebSub subscription for feed %v: %+v", sub.FeedID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to verify subscription")
+			return
+		}
+		if activated == 0 {
+			// denied since it was read
+			respondWithError(w, http.StatusNotFound, "Subscription was not requested")
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, query.Get("hub.challenge")); err != nil {
+			cfg.Logger.Printf("Failed to write WebSub challenge: %+v", err)
+		}
+
+	case "denied":
+		if err := cfg.DB.DenyWebsubSubscription(r.Context(), database.DenyWebsubSubscriptionParams{
+			FeedID:    sub.FeedID,
+			LastError: sql.NullString{String: query.Get("hub.reason"), Valid: true},
+			UpdatedAt: time.Now(),
+		}); err != nil {
+			cfg.Logger.Printf("Failed to record denied WebSub subscription for feed %v: %+v", sub.FeedID, err)
+		}
+		respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+
+	default:
+		// we never unsubscribe, so anything else was not requested by us
+		respondWithError(w, http.StatusNotFound, "Unexpected hub.mode")
+	}
+}
+
+// handlerWebsubContent ingests content pushed by the hub through the normal post pipeline.
+func (cfg *apiConfig) handlerWebsubContent(w http.ResponseWriter, r *http.Request) {
+	sub, ok := cfg.websubSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxWebsubContentSize)
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Content is too large")
+		return
+	}
+
+	// the spec asks for a 2xx even when the signature does not match, the content is dropped
+	if !websub.VerifySignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		cfg.Logger.Printf("Dropped WebSub content with an invalid signature for feed %v", sub.FeedID)
+		respondWithJSON(w, http.StatusAccepted, map[string]string{"status": "ignored"})
+		return
+	}
+
+	// a hub may push before verifying or after denying, only verified content is ingested
+	if sub.Status != websub.StatusActive {
+		cfg.Logger.Printf("Dropped WebSub content for the %s subscription of feed %v", sub.Status, sub.FeedID)
+		respondWithJSON(w, http.StatusAccepted, map[string]string{"status": "ignored"})
+		return
+	}
+
+	feed, err := cfg.DB.GetFeedByID(r.Context(), sub.FeedID)
+	if err != nil {
+		cfg.Logger.Printf("Failed to get feed %v for WebSub content: %+v", sub.FeedID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to ingest content")
+		return
+	}
+
+	result, err := scrapper.IngestFeed(r.Context(), cfg.DB, cfg.Events, feed, body)
+	if err != nil {
+		cfg.Logger.Printf("Failed to ingest WebSub content for feed %v: %+v", feed.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to ingest content")
+		return
+	}
+	cfg.Logger.Printf("Ingested %d new posts pushed for feed %v", result.NewPosts, feed.Url)
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// apiKeyPrefix marks keys issued by this server so they are easy to recognise,
+	// for example by secret scanners.
+	apiKeyPrefix = "bag_"
+	apiKeyBytes  = 32
+	// displayed prefix length, enough to tell a user's keys apart
+	prefixLength = len(apiKeyPrefix) + 8
+)
+
+// NewAPIKey is a freshly generated key. Key is only ever shown to the user once,
+// the database keeps Prefix and Hash.
+type NewAPIKey struct {
+	Key    string
+	Prefix string
+	Hash   string
+}
+
+func GenerateAPIKey() (NewAPIKey, error) {
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return NewAPIKey{}, errors.Wrap(err, "generating API key")
+	}
+
+	key := apiKeyPrefix + hex.EncodeToString(b)
+	return NewAPIKey{
+		Key:    key,
+		Prefix: key[:prefixLength],
+		Hash:   HashAPIKey(key),
+	}, nil
+}
+
+// HashAPIKey returns the hex SHA-256 of key. Keys are random, so a fast unsalted
+// hash is enough and lets the hash be looked up directly.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+)
+
+type apiKeyContextKey struct{}
+
+// WithAPIKey returns a copy of ctx carrying the API key that authenticated the request.
+func WithAPIKey(ctx context.Context, key database.ApiKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+// APIKeyFromContext returns the API key stored by WithAPIKey, if any.
+func APIKeyFromContext(ctx context.Context) (database.ApiKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(database.ApiKey)
+	return key, ok
+}
+
+type sessionContextKey struct{}
+
+// WithSession returns a copy of ctx carrying the session that authenticated the request.
+func WithSession(ctx context.Context, session database.Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// SessionFromContext returns the session stored by WithSession, if any.
+func SessionFromContext(ctx context.Context) (database.Session, bool) {
+	session, ok := ctx.Value(sessionContextKey{}).(database.Session)
+	return session, ok
+}
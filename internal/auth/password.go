@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	MinPasswordLength = 8
+	// MaxPasswordLength is the most bcrypt looks at, longer passwords would be cut silently
+	MaxPasswordLength = 72
+)
+
+// dummyHash is compared against when the user does not exist, so a login for an
+// unknown name takes as long as one with a wrong password.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", errors.Wrap(err, "hashing password")
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches hash. An empty hash never
+// matches, but still costs a bcrypt comparison.
+func CheckPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NewSession holds the secrets of a new session. Token goes in the session cookie
+// and only its Hash is stored, CSRFToken must accompany every unsafe request.
+type NewSession struct {
+	Token     string
+	Hash      string
+	CSRFToken string
+}
+
+func GenerateSession() (NewSession, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return NewSession{}, errors.Wrap(err, "generating session token")
+	}
+	csrfToken, err := randomHex(32)
+	if err != nil {
+		return NewSession{}, errors.Wrap(err, "generating CSRF token")
+	}
+	return NewSession{Token: token, Hash: HashSessionToken(token), CSRFToken: csrfToken}, nil
+}
+
+func HashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,38 @@
+package auth
+
+import "strings"
+
+// Scopes limit what an API key may do.
+const (
+	ScopePostsRead    = "posts:read"
+	ScopeFollowsRead  = "follows:read"
+	ScopeFollowsWrite = "follows:write"
+	ScopeFeedsWrite   = "feeds:write"
+	// ScopeAdmin grants every other scope, and manages API keys, webhooks,
+	// output feeds and digests.
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every scope a key can be given.
+var Scopes = []string{ScopePostsRead, ScopeFollowsRead, ScopeFollowsWrite, ScopeFeedsWrite, ScopeAdmin}
+
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether granted allows required. admin allows everything and
+// a write scope allows reading the same resource.
+func HasScope(granted []string, required string) bool {
+	resource, _, _ := strings.Cut(required, ":")
+	for _, g := range granted {
+		if g == required || g == ScopeAdmin || g == resource+":write" {
+			return true
+		}
+	}
+	return false
+}
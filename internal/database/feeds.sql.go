@@ -13,12 +13,52 @@ import (
 	"github.com/google/uuid"
 )
 
+const activateFeed = `-- name: ActivateFeed :one
+UPDATE feeds SET status = 'active', status_error = NULL, title = COALESCE(title_override, $2), description = $3, link = $4, updated_at = $5
+WHERE id = $1
+RETURNING id, created_at, updated_at, user_id, url, title, description, last_fetched_at, link, status, status_error, title_override
+`
+
+type ActivateFeedParams struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Link        string    `json:"link"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (q *Queries) ActivateFeed(ctx context.Context, arg ActivateFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, activateFeed,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.Link,
+		arg.UpdatedAt,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Url,
+		&i.Title,
+		&i.Description,
+		&i.LastFetchedAt,
+		&i.Link,
+		&i.Status,
+		&i.StatusError,
+		&i.TitleOverride,
+	)
+	return i, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (
-  id, created_at, updated_at, user_id, url, title, description
+  id, created_at, updated_at, user_id, url, title, description, link, status
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7
-) RETURNING id, created_at, updated_at, user_id, url, title, description, last_fetched_at
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING id, created_at, updated_at, user_id, url, title, description, last_fetched_at, link, status, status_error, title_override
 `
 
 type CreateFeedParams struct {
@@ -29,6 +69,8 @@ type CreateFeedParams struct {
 	Url         string    `json:"url"`
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
+	Link        string    `json:"link"`
+	Status      string    `json:"status"`
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -40,6 +82,8 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.Url,
 		arg.Title,
 		arg.Description,
+		arg.Link,
+		arg.Status,
 	)
 	var i Feed
 	err := row.Scan(
@@ -51,12 +95,28 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Title,
 		&i.Description,
 		&i.LastFetchedAt,
+		&i.Link,
+		&i.Status,
+		&i.StatusError,
+		&i.TitleOverride,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :execrows
+DELETE FROM feeds WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeed, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getFeedByID = `-- name: GetFeedByID :one
-SELECT id, created_at, updated_at, user_id, url, title, description, last_fetched_at FROM feeds WHERE id = $1
+SELECT id, created_at, updated_at, user_id, url, title, description, last_fetched_at, link, status, status_error, title_override FROM feeds WHERE id = $1
 `
 
 func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
@@ -71,12 +131,16 @@ func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
 		&i.Title,
 		&i.Description,
 		&i.LastFetchedAt,
+		&i.Link,
+		&i.Status,
+		&i.StatusError,
+		&i.TitleOverride,
 	)
 	return i, err
 }
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, user_id, url, title, description, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, user_id, url, title, description, last_fetched_at, link, status, status_error, title_override FROM feeds WHERE url = $1
 `
 
 func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
@@ -91,12 +155,16 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Title,
 		&i.Description,
 		&i.LastFetchedAt,
+		&i.Link,
+		&i.Status,
+		&i.StatusError,
+		&i.TitleOverride,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, user_id, url, title, description, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, user_id, url, title, description, last_fetched_at, link, status, status_error, title_override FROM feeds
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -117,6 +185,50 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Title,
 			&i.Description,
 			&i.LastFetchedAt,
+			&i.Link,
+			&i.Status,
+			&i.StatusError,
+			&i.TitleOverride,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsByOwner = `-- name: GetFeedsByOwner :many
+SELECT id, created_at, updated_at, user_id, url, title, description, last_fetched_at, link, status, status_error, title_override FROM feeds WHERE user_id = $1 ORDER BY created_at
+`
+
+func (q *Queries) GetFeedsByOwner(ctx context.Context, userID uuid.UUID) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsByOwner, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Url,
+			&i.Title,
+			&i.Description,
+			&i.LastFetchedAt,
+			&i.Link,
+			&i.Status,
+			&i.StatusError,
+			&i.TitleOverride,
 		); err != nil {
 			return nil, err
 		}
@@ -132,7 +244,7 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 }
 
 const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
-SELECT id, created_at, updated_at, user_id, url, title, description, last_fetched_at FROM feeds ORDER BY last_fetched_at ASC NULLS FIRST LIMIT $1
+SELECT id, created_at, updated_at, user_id, url, title, description, last_fetched_at, link, status, status_error, title_override FROM feeds WHERE status = 'active' ORDER BY last_fetched_at ASC NULLS FIRST LIMIT $1
 `
 
 func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed, error) {
@@ -153,6 +265,10 @@ func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed,
 			&i.Title,
 			&i.Description,
 			&i.LastFetchedAt,
+			&i.Link,
+			&i.Status,
+			&i.StatusError,
+			&i.TitleOverride,
 		); err != nil {
 			return nil, err
 		}
@@ -168,7 +284,7 @@ func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed,
 }
 
 const markFeedAsFetched = `-- name: MarkFeedAsFetched :one
-UPDATE feeds SET last_fetched_at = $2, updated_at = $3 WHERE id = $1 RETURNING id, created_at, updated_at, user_id, url, title, description, last_fetched_at
+UPDATE feeds SET last_fetched_at = $2, updated_at = $3 WHERE id = $1 RETURNING id, created_at, updated_at, user_id, url, title, description, last_fetched_at, link, status, status_error, title_override
 `
 
 type MarkFeedAsFetchedParams struct {
@@ -189,6 +305,124 @@ func (q *Queries) MarkFeedAsFetched(ctx context.Context, arg MarkFeedAsFetchedPa
 		&i.Title,
 		&i.Description,
 		&i.LastFetchedAt,
+		&i.Link,
+		&i.Status,
+		&i.StatusError,
+		&i.TitleOverride,
+	)
+	return i, err
+}
+
+const setFeedOwner = `-- name: SetFeedOwner :one
+UPDATE feeds SET user_id = $2, updated_at = $3
+WHERE id = $1
+RETURNING id, created_at, updated_at, user_id, url, title, description, last_fetched_at, link, status, status_error, title_override
+`
+
+type SetFeedOwnerParams struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (q *Queries) SetFeedOwner(ctx context.Context, arg SetFeedOwnerParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedOwner, arg.ID, arg.UserID, arg.UpdatedAt)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Url,
+		&i.Title,
+		&i.Description,
+		&i.LastFetchedAt,
+		&i.Link,
+		&i.Status,
+		&i.StatusError,
+		&i.TitleOverride,
+	)
+	return i, err
+}
+
+const setFeedStatus = `-- name: SetFeedStatus :one
+UPDATE feeds SET status = $2, status_error = $3, updated_at = $4
+WHERE id = $1
+RETURNING id, created_at, updated_at, user_id, url, title, description, last_fetched_at, link, status, status_error, title_override
+`
+
+type SetFeedStatusParams struct {
+	ID          uuid.UUID      `json:"id"`
+	Status      string         `json:"status"`
+	StatusError sql.NullString `json:"status_error"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+func (q *Queries) SetFeedStatus(ctx context.Context, arg SetFeedStatusParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedStatus,
+		arg.ID,
+		arg.Status,
+		arg.StatusError,
+		arg.UpdatedAt,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Url,
+		&i.Title,
+		&i.Description,
+		&i.LastFetchedAt,
+		&i.Link,
+		&i.Status,
+		&i.StatusError,
+		&i.TitleOverride,
+	)
+	return i, err
+}
+
+const updateFeed = `-- name: UpdateFeed :one
+UPDATE feeds SET url = $2, title = $3, title_override = $4, description = $5, link = $6, updated_at = $7
+WHERE id = $1
+RETURNING id, created_at, updated_at, user_id, url, title, description, last_fetched_at, link, status, status_error, title_override
+`
+
+type UpdateFeedParams struct {
+	ID            uuid.UUID      `json:"id"`
+	Url           string         `json:"url"`
+	Title         string         `json:"title"`
+	TitleOverride sql.NullString `json:"title_override"`
+	Description   string         `json:"description"`
+	Link          string         `json:"link"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+func (q *Queries) UpdateFeed(ctx context.Context, arg UpdateFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeed,
+		arg.ID,
+		arg.Url,
+		arg.Title,
+		arg.TitleOverride,
+		arg.Description,
+		arg.Link,
+		arg.UpdatedAt,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Url,
+		&i.Title,
+		&i.Description,
+		&i.LastFetchedAt,
+		&i.Link,
+		&i.Status,
+		&i.StatusError,
+		&i.TitleOverride,
 	)
 	return i, err
 }
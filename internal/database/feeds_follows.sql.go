@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,18 +15,19 @@ import (
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
 INSERT INTO feed_follows (
-  id, created_at, updated_at, feed_id, user_id
+  id, created_at, updated_at, feed_id, user_id, folder_id
   ) VALUES (
-  $1, $2, $3, $4, $5
-  ) RETURNING id, created_at, updated_at, feed_id, user_id
+  $1, $2, $3, $4, $5, $6
+  ) RETURNING id, created_at, updated_at, feed_id, user_id, folder_id, display_name, notify, hidden, last_read_at
 `
 
 type CreateFeedFollowParams struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	FeedID    uuid.UUID `json:"feed_id"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	FeedID    uuid.UUID     `json:"feed_id"`
+	UserID    uuid.UUID     `json:"user_id"`
+	FolderID  uuid.NullUUID `json:"folder_id"`
 }
 
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (FeedFollow, error) {
@@ -35,6 +37,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		arg.UpdatedAt,
 		arg.FeedID,
 		arg.UserID,
+		arg.FolderID,
 	)
 	var i FeedFollow
 	err := row.Scan(
@@ -43,6 +46,11 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.FeedID,
 		&i.UserID,
+		&i.FolderID,
+		&i.DisplayName,
+		&i.Notify,
+		&i.Hidden,
+		&i.LastReadAt,
 	)
 	return i, err
 }
@@ -62,7 +70,7 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 }
 
 const getFeedFollows = `-- name: GetFeedFollows :one
-SELECT id, created_at, updated_at, feed_id, user_id FROM feed_follows WHERE feed_id = $1 AND user_id = $2
+SELECT id, created_at, updated_at, feed_id, user_id, folder_id, display_name, notify, hidden, last_read_at FROM feed_follows WHERE feed_id = $1 AND user_id = $2
 `
 
 type GetFeedFollowsParams struct {
@@ -79,23 +87,51 @@ func (q *Queries) GetFeedFollows(ctx context.Context, arg GetFeedFollowsParams)
 		&i.UpdatedAt,
 		&i.FeedID,
 		&i.UserID,
+		&i.FolderID,
+		&i.DisplayName,
+		&i.Notify,
+		&i.Hidden,
+		&i.LastReadAt,
 	)
 	return i, err
 }
 
 const getFeedFollowsByUser = `-- name: GetFeedFollowsByUser :many
-SELECT id, created_at, updated_at, user_id, url, title, description, last_fetched_at FROM feeds WHERE id IN (SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = $1)
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.user_id, feeds.url, feeds.title, feeds.description, feeds.last_fetched_at, feeds.link, feeds.status, feeds.status_error, feeds.title_override, feed_follows.folder_id, feed_follows.display_name, feed_follows.notify, feed_follows.hidden
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.created_at, feeds.id
 `
 
-func (q *Queries) GetFeedFollowsByUser(ctx context.Context, userID uuid.UUID) ([]Feed, error) {
+type GetFeedFollowsByUserRow struct {
+	ID            uuid.UUID      `json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	UserID        uuid.UUID      `json:"user_id"`
+	Url           string         `json:"url"`
+	Title         string         `json:"title"`
+	Description   string         `json:"description"`
+	LastFetchedAt sql.NullTime   `json:"last_fetched_at"`
+	Link          string         `json:"link"`
+	Status        string         `json:"status"`
+	StatusError   sql.NullString `json:"status_error"`
+	TitleOverride sql.NullString `json:"title_override"`
+	FolderID      uuid.NullUUID  `json:"folder_id"`
+	DisplayName   sql.NullString `json:"display_name"`
+	Notify        bool           `json:"notify"`
+	Hidden        bool           `json:"hidden"`
+}
+
+func (q *Queries) GetFeedFollowsByUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsByUserRow, error) {
 	rows, err := q.db.QueryContext(ctx, getFeedFollowsByUser, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Feed
+	var items []GetFeedFollowsByUserRow
 	for rows.Next() {
-		var i Feed
+		var i GetFeedFollowsByUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -105,6 +141,62 @@ func (q *Queries) GetFeedFollowsByUser(ctx context.Context, userID uuid.UUID) ([
 			&i.Title,
 			&i.Description,
 			&i.LastFetchedAt,
+			&i.Link,
+			&i.Status,
+			&i.StatusError,
+			&i.TitleOverride,
+			&i.FolderID,
+			&i.DisplayName,
+			&i.Notify,
+			&i.Hidden,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedFollowsWithFolderByUser = `-- name: GetFeedFollowsWithFolderByUser :many
+SELECT feeds.id, feeds.url, COALESCE(feed_follows.display_name, feeds.title)::text AS title, feeds.description, feeds.link, folders.name AS folder_name
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+LEFT JOIN folders ON folders.id = feed_follows.folder_id
+WHERE feed_follows.user_id = $1
+ORDER BY folders.name NULLS FIRST, title
+`
+
+type GetFeedFollowsWithFolderByUserRow struct {
+	ID          uuid.UUID      `json:"id"`
+	Url         string         `json:"url"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Link        string         `json:"link"`
+	FolderName  sql.NullString `json:"folder_name"`
+}
+
+func (q *Queries) GetFeedFollowsWithFolderByUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsWithFolderByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsWithFolderByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedFollowsWithFolderByUserRow
+	for rows.Next() {
+		var i GetFeedFollowsWithFolderByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Title,
+			&i.Description,
+			&i.Link,
+			&i.FolderName,
 		); err != nil {
 			return nil, err
 		}
@@ -118,3 +210,142 @@ func (q *Queries) GetFeedFollowsByUser(ctx context.Context, userID uuid.UUID) ([
 	}
 	return items, nil
 }
+
+const getOldestOtherFollower = `-- name: GetOldestOtherFollower :one
+SELECT user_id FROM feed_follows
+WHERE feed_id = $1 AND user_id <> $2
+ORDER BY created_at, id
+LIMIT 1
+`
+
+type GetOldestOtherFollowerParams struct {
+	FeedID uuid.UUID `json:"feed_id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// Returns who has followed the feed the longest, apart from user_id.
+func (q *Queries) GetOldestOtherFollower(ctx context.Context, arg GetOldestOtherFollowerParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getOldestOtherFollower, arg.FeedID, arg.UserID)
+	var user_id uuid.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const getUnreadCountsByUser = `-- name: GetUnreadCountsByUser :many
+SELECT feed_follows.feed_id, COUNT(posts.id) AS unread
+FROM feed_follows
+LEFT JOIN posts ON posts.feed_id = feed_follows.feed_id AND posts.created_at > feed_follows.last_read_at
+WHERE feed_follows.user_id = $1
+  AND NOT feed_follows.hidden
+  AND ($2::uuid IS NULL OR feed_follows.folder_id = $2)
+GROUP BY feed_follows.feed_id, feed_follows.created_at
+ORDER BY feed_follows.created_at, feed_follows.feed_id
+`
+
+type GetUnreadCountsByUserParams struct {
+	UserID   uuid.UUID     `json:"user_id"`
+	FolderID uuid.NullUUID `json:"folder_id"`
+}
+
+type GetUnreadCountsByUserRow struct {
+	FeedID uuid.UUID `json:"feed_id"`
+	Unread int64     `json:"unread"`
+}
+
+func (q *Queries) GetUnreadCountsByUser(ctx context.Context, arg GetUnreadCountsByUserParams) ([]GetUnreadCountsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadCountsByUser, arg.UserID, arg.FolderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadCountsByUserRow
+	for rows.Next() {
+		var i GetUnreadCountsByUserRow
+		if err := rows.Scan(
+			&i.FeedID,
+			&i.Unread,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedFollowRead = `-- name: MarkFeedFollowRead :one
+UPDATE feed_follows SET last_read_at = $3, updated_at = $3
+WHERE feed_id = $1 AND user_id = $2
+RETURNING id, created_at, updated_at, feed_id, user_id, folder_id, display_name, notify, hidden, last_read_at
+`
+
+type MarkFeedFollowReadParams struct {
+	FeedID     uuid.UUID `json:"feed_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	LastReadAt time.Time `json:"last_read_at"`
+}
+
+func (q *Queries) MarkFeedFollowRead(ctx context.Context, arg MarkFeedFollowReadParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, markFeedFollowRead, arg.FeedID, arg.UserID, arg.LastReadAt)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.UserID,
+		&i.FolderID,
+		&i.DisplayName,
+		&i.Notify,
+		&i.Hidden,
+		&i.LastReadAt,
+	)
+	return i, err
+}
+
+const updateFeedFollow = `-- name: UpdateFeedFollow :one
+UPDATE feed_follows SET folder_id = $3, display_name = $4, notify = $5, hidden = $6, updated_at = $7
+WHERE feed_id = $1 AND user_id = $2
+RETURNING id, created_at, updated_at, feed_id, user_id, folder_id, display_name, notify, hidden, last_read_at
+`
+
+type UpdateFeedFollowParams struct {
+	FeedID      uuid.UUID      `json:"feed_id"`
+	UserID      uuid.UUID      `json:"user_id"`
+	FolderID    uuid.NullUUID  `json:"folder_id"`
+	DisplayName sql.NullString `json:"display_name"`
+	Notify      bool           `json:"notify"`
+	Hidden      bool           `json:"hidden"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+func (q *Queries) UpdateFeedFollow(ctx context.Context, arg UpdateFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedFollow,
+		arg.FeedID,
+		arg.UserID,
+		arg.FolderID,
+		arg.DisplayName,
+		arg.Notify,
+		arg.Hidden,
+		arg.UpdatedAt,
+	)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.UserID,
+		&i.FolderID,
+		&i.DisplayName,
+		&i.Notify,
+		&i.Hidden,
+		&i.LastReadAt,
+	)
+	return i, err
+}
@@ -6,45 +6,187 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type ApiKey struct {
+	ID         uuid.UUID     `json:"id"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+	UserID     uuid.UUID     `json:"user_id"`
+	Name       string        `json:"name"`
+	Prefix     string        `json:"prefix"`
+	KeyHash    string        `json:"-"`
+	LastUsedAt sql.NullTime  `json:"last_used_at"`
+	RevokedAt  sql.NullTime  `json:"revoked_at"`
+	ExpiresAt  sql.NullTime  `json:"expires_at"`
+	ReplacedBy uuid.NullUUID `json:"replaced_by"`
+	Scopes     []string      `json:"scopes"`
+}
+
+type DigestSetting struct {
+	UserID           uuid.UUID    `json:"user_id"`
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+	Email            string       `json:"email"`
+	Frequency        string       `json:"frequency"`
+	Timezone         string       `json:"timezone"`
+	SendHour         int32        `json:"send_hour"`
+	SendWeekday      int32        `json:"send_weekday"`
+	LastSentAt       sql.NullTime `json:"last_sent_at"`
+	UnsubscribeToken string       `json:"-"`
+}
+
 type Feed struct {
-	ID            uuid.UUID    `json:"id"`
-	CreatedAt     time.Time    `json:"created_at"`
-	UpdatedAt     time.Time    `json:"updated_at"`
-	UserID        uuid.UUID    `json:"user_id"`
-	Url           string       `json:"url"`
-	Title         string       `json:"title"`
-	Description   string       `json:"description"`
-	LastFetchedAt sql.NullTime `json:"last_fetched_at"`
+	ID            uuid.UUID      `json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	UserID        uuid.UUID      `json:"user_id"`
+	Url           string         `json:"url"`
+	Title         string         `json:"title"`
+	Description   string         `json:"description"`
+	LastFetchedAt sql.NullTime   `json:"last_fetched_at"`
+	Link          string         `json:"link"`
+	Status        string         `json:"status"`
+	StatusError   sql.NullString `json:"status_error"`
+	TitleOverride sql.NullString `json:"title_override"`
 }
 
 type FeedFollow struct {
+	ID          uuid.UUID      `json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	FeedID      uuid.UUID      `json:"feed_id"`
+	UserID      uuid.UUID      `json:"user_id"`
+	FolderID    uuid.NullUUID  `json:"folder_id"`
+	DisplayName sql.NullString `json:"display_name"`
+	Notify      bool           `json:"notify"`
+	Hidden      bool           `json:"hidden"`
+	LastReadAt  time.Time      `json:"last_read_at"`
+}
+
+type Folder struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-	FeedID    uuid.UUID `json:"feed_id"`
 	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+}
+
+type Job struct {
+	ID         uuid.UUID      `json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	UserID     uuid.UUID      `json:"user_id"`
+	FeedID     uuid.UUID      `json:"feed_id"`
+	Kind       string         `json:"kind"`
+	Status     string         `json:"status"`
+	NewPosts   sql.NullInt32  `json:"new_posts"`
+	Error      sql.NullString `json:"error"`
+	StartedAt  sql.NullTime   `json:"started_at"`
+	FinishedAt sql.NullTime   `json:"finished_at"`
+}
+
+type OutputFeed struct {
+	ID        uuid.UUID      `json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	UserID    uuid.UUID      `json:"user_id"`
+	Name      string         `json:"name"`
+	Token     string         `json:"token"`
+	FolderID  uuid.NullUUID  `json:"folder_id"`
+	Query     sql.NullString `json:"query"`
 }
 
 type Post struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	FeedID      uuid.UUID `json:"feed_id"`
-	Title       string    `json:"title"`
-	Url         string    `json:"url"`
-	Description string    `json:"description"`
-	PublishDate time.Time `json:"publish_date"`
+	ID           uuid.UUID `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	FeedID       uuid.UUID `json:"feed_id"`
+	Title        string    `json:"title"`
+	Url          string    `json:"url"`
+	Description  string    `json:"description"`
+	PublishDate  time.Time `json:"publish_date"`
+	Content      string    `json:"content"`
+	SearchVector string    `json:"-"`
+}
+
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	UserID     uuid.UUID `json:"user_id"`
+	TokenHash  string    `json:"-"`
+	CsrfToken  string    `json:"csrf_token"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
 }
 
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Name      string    `json:"name"`
-	ApiKey    string    `json:"api_key"`
+	ID           uuid.UUID      `json:"id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	Name         string         `json:"name"`
+	PasswordHash sql.NullString `json:"-"`
+	IsAdmin      bool           `json:"is_admin"`
+	DisabledAt   sql.NullTime   `json:"disabled_at"`
+}
+
+type UserIdentity struct {
+	ID          uuid.UUID      `json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	UserID      uuid.UUID      `json:"user_id"`
+	Issuer      string         `json:"issuer"`
+	Subject     string         `json:"subject"`
+	Email       sql.NullString `json:"email"`
+	LastLoginAt time.Time      `json:"last_login_at"`
+}
+
+type Webhook struct {
+	ID        uuid.UUID      `json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	UserID    uuid.UUID      `json:"user_id"`
+	Url       string         `json:"url"`
+	Secret    string         `json:"-"`
+	FeedID    uuid.NullUUID  `json:"feed_id"`
+	Keyword   sql.NullString `json:"keyword"`
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID       `json:"id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	WebhookID     uuid.UUID       `json:"webhook_id"`
+	PostID        uuid.NullUUID   `json:"post_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastAttemptAt sql.NullTime    `json:"last_attempt_at"`
+	ResponseCode  sql.NullInt32   `json:"response_code"`
+	LastError     sql.NullString  `json:"last_error"`
+}
+
+type WebhookSweep struct {
+	ID         bool      `json:"id"`
+	SweptUntil time.Time `json:"swept_until"`
+	LastPostID uuid.UUID `json:"last_post_id"`
+}
+
+type WebsubSubscription struct {
+	FeedID         uuid.UUID      `json:"feed_id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	HubUrl         string         `json:"hub_url"`
+	TopicUrl       string         `json:"topic_url"`
+	Secret         string         `json:"secret"`
+	Status         string         `json:"status"`
+	LeaseExpiresAt sql.NullTime   `json:"lease_expires_at"`
+	LastError      sql.NullString `json:"last_error"`
 }
@@ -7,17 +7,19 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createPost = `-- name: CreatePost :one
 INSERT INTO posts (
-  id, created_at, updated_at, feed_id, title, url, description, publish_date
+  id, created_at, updated_at, feed_id, title, url, description, publish_date, content
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8
-) RETURNING id, created_at, updated_at, feed_id, title, url, description, publish_date
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING id, created_at, updated_at, feed_id, title, url, description, publish_date, content, search_vector
 `
 
 type CreatePostParams struct {
@@ -29,6 +31,7 @@ type CreatePostParams struct {
 	Url         string    `json:"url"`
 	Description string    `json:"description"`
 	PublishDate time.Time `json:"publish_date"`
+	Content     string    `json:"content"`
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -41,6 +44,7 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Url,
 		arg.Description,
 		arg.PublishDate,
+		arg.Content,
 	)
 	var i Post
 	err := row.Scan(
@@ -52,12 +56,184 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Url,
 		&i.Description,
 		&i.PublishDate,
+		&i.Content,
+		&i.SearchVector,
+	)
+	return i, err
+}
+
+const getFilteredPostsByUser = `-- name: GetFilteredPostsByUser :many
+SELECT id, created_at, updated_at, feed_id, title, url, description, publish_date, content, search_vector FROM posts
+WHERE feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = $1
+      AND ($2::uuid IS NULL OR feed_follows.folder_id = $2)
+      -- hidden feeds only show up when asked for by id
+      AND (NOT feed_follows.hidden OR feed_follows.feed_id = ANY($3::uuid[]))
+  )
+  AND ($4::timestamptz IS NULL OR publish_date >= $4)
+  AND ($5::timestamptz IS NULL OR publish_date < $5)
+  AND (cardinality($3::uuid[]) = 0 OR feed_id = ANY($3::uuid[]))
+  AND ($6::text IS NULL OR search_vector @@ to_tsquery('english', $6))
+  AND ($7::timestamptz IS NULL OR (publish_date, id) < ($7::timestamptz, $8::uuid))
+ORDER BY publish_date DESC, id DESC
+LIMIT $9
+`
+
+type GetFilteredPostsByUserParams struct {
+	UserID     uuid.UUID      `json:"user_id"`
+	FolderID   uuid.NullUUID  `json:"folder_id"`
+	FeedIds    []uuid.UUID    `json:"feed_ids"`
+	Since      sql.NullTime   `json:"since"`
+	Until      sql.NullTime   `json:"until"`
+	Query      sql.NullString `json:"query"`
+	BeforeDate sql.NullTime   `json:"before_date"`
+	BeforeID   uuid.NullUUID  `json:"before_id"`
+	PageLimit  int32          `json:"page_limit"`
+}
+
+func (q *Queries) GetFilteredPostsByUser(ctx context.Context, arg GetFilteredPostsByUserParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getFilteredPostsByUser,
+		arg.UserID,
+		arg.FolderID,
+		pq.Array(arg.FeedIds),
+		arg.Since,
+		arg.Until,
+		arg.Query,
+		arg.BeforeDate,
+		arg.BeforeID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishDate,
+			&i.Content,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFilteredPostsByUserIngested = `-- name: GetFilteredPostsByUserIngested :many
+SELECT id, created_at, updated_at, feed_id, title, url, description, publish_date, content, search_vector FROM posts
+WHERE feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = $1
+      AND ($2::uuid IS NULL OR feed_follows.folder_id = $2)
+      -- hidden feeds only show up when asked for by id
+      AND (NOT feed_follows.hidden OR feed_follows.feed_id = ANY($3::uuid[]))
+  )
+  AND ($4::timestamptz IS NULL OR created_at >= $4)
+  AND ($5::timestamptz IS NULL OR created_at < $5)
+  AND (cardinality($3::uuid[]) = 0 OR feed_id = ANY($3::uuid[]))
+  AND ($6::text IS NULL OR search_vector @@ to_tsquery('english', $6))
+  AND ($7::timestamptz IS NULL OR (created_at, id) < ($7::timestamptz, $8::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $9
+`
+
+type GetFilteredPostsByUserIngestedParams struct {
+	UserID     uuid.UUID      `json:"user_id"`
+	FolderID   uuid.NullUUID  `json:"folder_id"`
+	FeedIds    []uuid.UUID    `json:"feed_ids"`
+	Since      sql.NullTime   `json:"since"`
+	Until      sql.NullTime   `json:"until"`
+	Query      sql.NullString `json:"query"`
+	BeforeDate sql.NullTime   `json:"before_date"`
+	BeforeID   uuid.NullUUID  `json:"before_id"`
+	PageLimit  int32          `json:"page_limit"`
+}
+
+func (q *Queries) GetFilteredPostsByUserIngested(ctx context.Context, arg GetFilteredPostsByUserIngestedParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getFilteredPostsByUserIngested,
+		arg.UserID,
+		arg.FolderID,
+		pq.Array(arg.FeedIds),
+		arg.Since,
+		arg.Until,
+		arg.Query,
+		arg.BeforeDate,
+		arg.BeforeID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishDate,
+			&i.Content,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostByID = `-- name: GetPostByID :one
+SELECT id, created_at, updated_at, feed_id, title, url, description, publish_date, content, search_vector FROM posts WHERE id = $1
+`
+
+func (q *Queries) GetPostByID(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByID, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishDate,
+		&i.Content,
+		&i.SearchVector,
 	)
 	return i, err
 }
 
 const getPostByURL = `-- name: GetPostByURL :one
-SELECT id, created_at, updated_at, feed_id, title, url, description, publish_date FROM posts WHERE url = $1
+SELECT id, created_at, updated_at, feed_id, title, url, description, publish_date, content, search_vector FROM posts WHERE url = $1
 `
 
 func (q *Queries) GetPostByURL(ctx context.Context, url string) (Post, error) {
@@ -72,12 +248,14 @@ func (q *Queries) GetPostByURL(ctx context.Context, url string) (Post, error) {
 		&i.Url,
 		&i.Description,
 		&i.PublishDate,
+		&i.Content,
+		&i.SearchVector,
 	)
 	return i, err
 }
 
 const getPostsByFeedID = `-- name: GetPostsByFeedID :many
-SELECT id, created_at, updated_at, feed_id, title, url, description, publish_date FROM posts WHERE feed_id = $1 ORDER BY publish_date DESC OFFSET $2 LIMIT $3
+SELECT id, created_at, updated_at, feed_id, title, url, description, publish_date, content, search_vector FROM posts WHERE feed_id = $1 ORDER BY publish_date DESC OFFSET $2 LIMIT $3
 `
 
 type GetPostsByFeedIDParams struct {
@@ -104,6 +282,160 @@ func (q *Queries) GetPostsByFeedID(ctx context.Context, arg GetPostsByFeedIDPara
 			&i.Url,
 			&i.Description,
 			&i.PublishDate,
+			&i.Content,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsByFeedIDPage = `-- name: GetPostsByFeedIDPage :many
+SELECT id, created_at, updated_at, feed_id, title, url, description, publish_date, content, search_vector FROM posts
+WHERE feed_id = $1
+  AND ($2::timestamptz IS NULL OR (publish_date, id) < ($2::timestamptz, $3::uuid))
+ORDER BY publish_date DESC, id DESC
+LIMIT $4
+`
+
+type GetPostsByFeedIDPageParams struct {
+	FeedID     uuid.UUID     `json:"feed_id"`
+	BeforeDate sql.NullTime  `json:"before_date"`
+	BeforeID   uuid.NullUUID `json:"before_id"`
+	PageLimit  int32         `json:"page_limit"`
+}
+
+func (q *Queries) GetPostsByFeedIDPage(ctx context.Context, arg GetPostsByFeedIDPageParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByFeedIDPage,
+		arg.FeedID,
+		arg.BeforeDate,
+		arg.BeforeID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishDate,
+			&i.Content,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsByFeedOwner = `-- name: GetPostsByFeedOwner :many
+SELECT id, created_at, updated_at, feed_id, title, url, description, publish_date, content, search_vector FROM posts WHERE feed_id IN (SELECT id FROM feeds WHERE user_id = $1) ORDER BY publish_date DESC OFFSET $2 LIMIT $3
+`
+
+type GetPostsByFeedOwnerParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	Offset int32     `json:"offset"`
+	Limit  int32     `json:"limit"`
+}
+
+func (q *Queries) GetPostsByFeedOwner(ctx context.Context, arg GetPostsByFeedOwnerParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByFeedOwner, arg.UserID, arg.Offset, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishDate,
+			&i.Content,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsByFeedOwnerPage = `-- name: GetPostsByFeedOwnerPage :many
+SELECT id, created_at, updated_at, feed_id, title, url, description, publish_date, content, search_vector FROM posts
+WHERE feed_id IN (SELECT id FROM feeds WHERE user_id = $1)
+  AND ($2::timestamptz IS NULL OR (publish_date, id) < ($2::timestamptz, $3::uuid))
+ORDER BY publish_date DESC, id DESC
+LIMIT $4
+`
+
+type GetPostsByFeedOwnerPageParams struct {
+	UserID     uuid.UUID     `json:"user_id"`
+	BeforeDate sql.NullTime  `json:"before_date"`
+	BeforeID   uuid.NullUUID `json:"before_id"`
+	PageLimit  int32         `json:"page_limit"`
+}
+
+func (q *Queries) GetPostsByFeedOwnerPage(ctx context.Context, arg GetPostsByFeedOwnerPageParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByFeedOwnerPage,
+		arg.UserID,
+		arg.BeforeDate,
+		arg.BeforeID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishDate,
+			&i.Content,
+			&i.SearchVector,
 		); err != nil {
 			return nil, err
 		}
@@ -119,7 +451,7 @@ func (q *Queries) GetPostsByFeedID(ctx context.Context, arg GetPostsByFeedIDPara
 }
 
 const getPostsByUser = `-- name: GetPostsByUser :many
-SELECT id, created_at, updated_at, feed_id, title, url, description, publish_date FROM posts WHERE feed_id IN (SELECT id FROM feeds WHERE user_id = $1) ORDER BY publish_date DESC OFFSET $2 LIMIT $3
+SELECT id, created_at, updated_at, feed_id, title, url, description, publish_date, content, search_vector FROM posts WHERE feed_id IN (SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = $1 AND NOT feed_follows.hidden) ORDER BY publish_date DESC OFFSET $2 LIMIT $3
 `
 
 type GetPostsByUserParams struct {
@@ -146,6 +478,140 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 			&i.Url,
 			&i.Description,
 			&i.PublishDate,
+			&i.Content,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsByUserCreatedAfter = `-- name: GetPostsByUserCreatedAfter :many
+SELECT id, created_at, updated_at, feed_id, title, url, description, publish_date, content, search_vector FROM posts
+WHERE feed_id IN (SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = $1 AND NOT feed_follows.hidden)
+  AND (created_at, id) > ($2::timestamptz, $3::uuid)
+ORDER BY created_at, id
+LIMIT $4
+`
+
+type GetPostsByUserCreatedAfterParams struct {
+	UserID    uuid.UUID `json:"user_id"`
+	AfterDate time.Time `json:"after_date"`
+	AfterID   uuid.UUID `json:"after_id"`
+	PageLimit int32     `json:"page_limit"`
+}
+
+func (q *Queries) GetPostsByUserCreatedAfter(ctx context.Context, arg GetPostsByUserCreatedAfterParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByUserCreatedAfter,
+		arg.UserID,
+		arg.AfterDate,
+		arg.AfterID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishDate,
+			&i.Content,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchPostsByUser = `-- name: SearchPostsByUser :many
+SELECT
+  posts.id, posts.created_at, posts.updated_at, posts.feed_id, posts.title, posts.url, posts.description, posts.publish_date,
+  ts_rank_cd(posts.search_vector, query)::real AS rank,
+  -- tags are stripped and matches delimited by chr(2) and chr(3), search.Snippet
+  -- escapes the text and turns the delimiters into <mark>
+  ts_headline(
+    'english',
+    regexp_replace(posts.title || ' ' || posts.description || ' ' || posts.content, '<[^>]*>', ' ', 'g'),
+    query,
+    'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2, MaxWords=30, MinWords=10'
+  ) AS snippet
+FROM posts, to_tsquery('english', $1) query
+WHERE posts.feed_id IN (SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = $2 AND NOT feed_follows.hidden)
+  AND posts.search_vector @@ query
+ORDER BY rank DESC, posts.publish_date DESC, posts.id DESC
+OFFSET $3 LIMIT $4
+`
+
+type SearchPostsByUserParams struct {
+	Query     string    `json:"query"`
+	UserID    uuid.UUID `json:"user_id"`
+	RowOffset int32     `json:"row_offset"`
+	RowLimit  int32     `json:"row_limit"`
+}
+
+type SearchPostsByUserRow struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	FeedID      uuid.UUID `json:"feed_id"`
+	Title       string    `json:"title"`
+	Url         string    `json:"url"`
+	Description string    `json:"description"`
+	PublishDate time.Time `json:"publish_date"`
+	Rank        float32   `json:"rank"`
+	Snippet     string    `json:"snippet"`
+}
+
+func (q *Queries) SearchPostsByUser(ctx context.Context, arg SearchPostsByUserParams) ([]SearchPostsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPostsByUser,
+		arg.Query,
+		arg.UserID,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsByUserRow
+	for rows.Next() {
+		var i SearchPostsByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishDate,
+			&i.Rank,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
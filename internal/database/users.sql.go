@@ -7,22 +7,25 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
-VALUES ($1, $2, $3, $4)
-RETURNING id, created_at, updated_at, name, api_key
+INSERT INTO users (id, created_at, updated_at, name, password_hash)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at, updated_at, name, password_hash, is_admin, disabled_at
 `
 
 type CreateUserParams struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Name      string    `json:"name"`
+	ID           uuid.UUID      `json:"id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	Name         string         `json:"name"`
+	PasswordHash sql.NullString `json:"password_hash"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -31,6 +34,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.Name,
+		arg.PasswordHash,
 	)
 	var i User
 	err := row.Scan(
@@ -38,39 +42,83 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
-		&i.ApiKey,
+		&i.PasswordHash,
+		&i.IsAdmin,
+		&i.DisabledAt,
 	)
 	return i, err
 }
 
-const deleteUser = `-- name: DeleteUser :exec
+const createUserIfNameFree = `-- name: CreateUserIfNameFree :one
+INSERT INTO users (id, created_at, updated_at, name, password_hash)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (name) DO NOTHING
+RETURNING id, created_at, updated_at, name, password_hash, is_admin, disabled_at
+`
+
+type CreateUserIfNameFreeParams struct {
+	ID           uuid.UUID      `json:"id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	Name         string         `json:"name"`
+	PasswordHash sql.NullString `json:"password_hash"`
+}
+
+// Returns no rows when the name is taken, which unlike an error keeps a transaction usable.
+func (q *Queries) CreateUserIfNameFree(ctx context.Context, arg CreateUserIfNameFreeParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, createUserIfNameFree,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.PasswordHash,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.PasswordHash,
+		&i.IsAdmin,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const deleteUser = `-- name: DeleteUser :execrows
 DELETE FROM users WHERE id = $1
 `
 
-func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteUser, id)
-	return err
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, name, api_key FROM users WHERE api_key = $1
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, name, password_hash, is_admin, disabled_at FROM users WHERE id = $1
 `
 
-func (q *Queries) GetUser(ctx context.Context, apiKey string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUser, apiKey)
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
 	var i User
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
-		&i.ApiKey,
+		&i.PasswordHash,
+		&i.IsAdmin,
+		&i.DisabledAt,
 	)
 	return i, err
 }
 
 const getUserByName = `-- name: GetUserByName :one
-SELECT id, created_at, updated_at, name, api_key FROM users WHERE name = $1
+SELECT id, created_at, updated_at, name, password_hash, is_admin, disabled_at FROM users WHERE name = $1
 `
 
 func (q *Queries) GetUserByName(ctx context.Context, name string) (User, error) {
@@ -81,17 +129,29 @@ func (q *Queries) GetUserByName(ctx context.Context, name string) (User, error)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
-		&i.ApiKey,
+		&i.PasswordHash,
+		&i.IsAdmin,
+		&i.DisabledAt,
 	)
 	return i, err
 }
 
 const getUsers = `-- name: GetUsers :many
-SELECT id, created_at, updated_at, name, api_key FROM users
+SELECT id, created_at, updated_at, name, password_hash, is_admin, disabled_at FROM users
+WHERE $1::text IS NULL OR name ILIKE '%' || $1 || '%'
+ORDER BY created_at, id
+LIMIT $2 OFFSET $3
 `
 
-func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
-	rows, err := q.db.QueryContext(ctx, getUsers)
+type GetUsersParams struct {
+	Query     sql.NullString `json:"query"`
+	RowLimit  int32          `json:"row_limit"`
+	RowOffset int32          `json:"row_offset"`
+}
+
+// Lists users for administrators, optionally only those whose name contains query.
+func (q *Queries) GetUsers(ctx context.Context, arg GetUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsers, arg.Query, arg.RowLimit, arg.RowOffset)
 	if err != nil {
 		return nil, err
 	}
@@ -104,7 +164,9 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Name,
-			&i.ApiKey,
+			&i.PasswordHash,
+			&i.IsAdmin,
+			&i.DisabledAt,
 		); err != nil {
 			return nil, err
 		}
@@ -119,11 +181,83 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
+const promoteUsersToAdmin = `-- name: PromoteUsersToAdmin :execrows
+UPDATE users SET is_admin = true, updated_at = $1
+WHERE id = ANY($2::uuid[]) AND NOT is_admin
+`
+
+type PromoteUsersToAdminParams struct {
+	UpdatedAt time.Time   `json:"updated_at"`
+	Ids       []uuid.UUID `json:"ids"`
+}
+
+func (q *Queries) PromoteUsersToAdmin(ctx context.Context, arg PromoteUsersToAdminParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, promoteUsersToAdmin, arg.UpdatedAt, pq.Array(arg.Ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserAdmin = `-- name: SetUserAdmin :one
+UPDATE users SET is_admin = $2, updated_at = $3
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, password_hash, is_admin, disabled_at
+`
+
+type SetUserAdminParams struct {
+	ID        uuid.UUID `json:"id"`
+	IsAdmin   bool      `json:"is_admin"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserAdmin, arg.ID, arg.IsAdmin, arg.UpdatedAt)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.PasswordHash,
+		&i.IsAdmin,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const setUserDisabled = `-- name: SetUserDisabled :one
+UPDATE users SET disabled_at = $2, updated_at = $3
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, password_hash, is_admin, disabled_at
+`
+
+type SetUserDisabledParams struct {
+	ID         uuid.UUID    `json:"id"`
+	DisabledAt sql.NullTime `json:"disabled_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+}
+
+func (q *Queries) SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserDisabled, arg.ID, arg.DisabledAt, arg.UpdatedAt)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.PasswordHash,
+		&i.IsAdmin,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET updated_at = $2, name = $3
 WHERE id = $1
-RETURNING id, created_at, updated_at, name, api_key
+RETURNING id, created_at, updated_at, name, password_hash, is_admin, disabled_at
 `
 
 type UpdateUserParams struct {
@@ -140,7 +274,9 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
-		&i.ApiKey,
+		&i.PasswordHash,
+		&i.IsAdmin,
+		&i.DisabledAt,
 	)
 	return i, err
 }
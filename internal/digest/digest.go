@@ -0,0 +1,247 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	htmltemplate "html/template"
+	"log"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/pkg/errors"
+
+	// digests are scheduled in the user's timezone, which must work without system tzdata
+	_ "time/tzdata"
+)
+
+const (
+	FrequencyOff    = "off"
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+)
+
+const (
+	checkInterval  = 5 * time.Minute
+	maxDigestPosts = 100
+)
+
+// LastScheduled returns the most recent time at or before now that a digest
+// with settings s was due.
+func LastScheduled(s database.DigestSetting, now time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "loading timezone %q", s.Timezone)
+	}
+
+	local := now.In(loc)
+	t := time.Date(local.Year(), local.Month(), local.Day(), int(s.SendHour), 0, 0, 0, loc)
+	if t.After(local) {
+		t = t.AddDate(0, 0, -1)
+	}
+	if s.Frequency == FrequencyWeekly {
+		for t.Weekday() != time.Weekday(s.SendWeekday) {
+			t = t.AddDate(0, 0, -1)
+		}
+	}
+	return t, nil
+}
+
+func period(frequency string) time.Duration {
+	if frequency == FrequencyWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Sender emails each user with digests enabled the posts of their followed feeds
+// that arrived since their previous digest.
+type Sender struct {
+	db     *database.Queries
+	smtp   SMTPConfig
+	logger *log.Logger
+	// baseURL is used to build unsubscribe links
+	baseURL string
+}
+
+func NewSender(db *database.Queries, smtp SMTPConfig, baseURL string, logger *log.Logger) *Sender {
+	return &Sender{db: db, smtp: smtp, logger: logger, baseURL: baseURL}
+}
+
+// UnsubscribeURL is the public link that turns digests off for a settings row.
+func (s *Sender) UnsubscribeURL(settings database.DigestSetting) string {
+	return s.baseURL + "/v1/digest/unsubscribe/" + settings.UnsubscribeToken
+}
+
+// Run sends due digests until ctx is done.
+func (s *Sender) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sender) sendDue(ctx context.Context) {
+	all, err := s.db.GetActiveDigestSettings(ctx)
+	if err != nil {
+		s.logger.Printf("Failed to get digest settings: %+v", err)
+		return
+	}
+
+	// Postgres keeps microseconds, ClaimDigest compares against the stored value
+	now := time.Now().Truncate(time.Microsecond)
+	for _, settings := range all {
+		scheduled, err := LastScheduled(settings, now)
+		if err != nil {
+			s.logger.Printf("Failed to schedule digest for user %v: %+v", settings.UserID, err)
+			continue
+		}
+		if settings.LastSentAt.Valid && !settings.LastSentAt.Time.Before(scheduled) {
+			continue
+		}
+		if err := s.send(ctx, settings, now); err != nil {
+			s.logger.Printf("Failed to send digest to user %v: %+v", settings.UserID, err)
+		}
+	}
+}
+
+func (s *Sender) send(ctx context.Context, settings database.DigestSetting, now time.Time) error {
+	since := now.Add(-period(settings.Frequency))
+	if settings.LastSentAt.Valid {
+		since = settings.LastSentAt.Time
+	}
+
+	// claim the digest first so two server instances never send it twice
+	claimed, err := s.db.ClaimDigest(ctx, database.ClaimDigestParams{
+		SentAt:         sql.NullTime{Time: now, Valid: true},
+		UserID:         settings.UserID,
+		PreviousSentAt: settings.LastSentAt,
+	})
+	if err != nil {
+		return errors.Wrap(err, "claiming digest")
+	}
+	if claimed == 0 {
+		return nil
+	}
+
+	posts, err := s.db.GetPostsForDigest(ctx, database.GetPostsForDigestParams{
+		UserID:    settings.UserID,
+		CreatedAt: since,
+		Limit:     maxDigestPosts,
+	})
+	if err != nil {
+		return s.release(ctx, settings, now, errors.Wrap(err, "getting posts"))
+	}
+	if len(posts) == 0 {
+		return nil
+	}
+
+	// last_sent_at already moved to now, so the posts past the limit are only counted
+	more := 0
+	if len(posts) == maxDigestPosts {
+		total, err := s.db.CountPostsForDigest(ctx, database.CountPostsForDigestParams{
+			UserID:    settings.UserID,
+			CreatedAt: since,
+		})
+		if err != nil {
+			return s.release(ctx, settings, now, errors.Wrap(err, "counting posts"))
+		}
+		more = int(total) - len(posts)
+	}
+
+	user, err := s.db.GetUserByID(ctx, settings.UserID)
+	if err != nil {
+		return s.release(ctx, settings, now, errors.Wrap(err, "getting user"))
+	}
+
+	msg, err := render(user, settings, posts, more, s.UnsubscribeURL(settings))
+	if err != nil {
+		return s.release(ctx, settings, now, err)
+	}
+	if err := s.smtp.send(msg); err != nil {
+		return s.release(ctx, settings, now, err)
+	}
+	return nil
+}
+
+// release undoes a claim so the digest is tried again on the next check.
+func (s *Sender) release(ctx context.Context, settings database.DigestSetting, claimedAt time.Time, cause error) error {
+	if _, err := s.db.ClaimDigest(ctx, database.ClaimDigestParams{
+		SentAt:         settings.LastSentAt,
+		UserID:         settings.UserID,
+		PreviousSentAt: sql.NullTime{Time: claimedAt, Valid: true},
+	}); err != nil {
+		s.logger.Printf("Failed to release digest claim for user %v: %+v", settings.UserID, err)
+	}
+	return cause
+}
+
+type templateData struct {
+	Name           string
+	Frequency      string
+	Posts          []database.GetPostsForDigestRow
+	More           int
+	UnsubscribeURL string
+}
+
+var textTemplate = texttemplate.Must(texttemplate.New("text").Parse(`Hi {{.Name}},
+
+Here is your {{.Frequency}} digest with {{len .Posts}} new post(s).
+{{range .Posts}}
+{{.Title}}
+{{.FeedTitle}} - {{.PublishDate.Format "Jan 2, 2006"}}
+{{.Url}}
+{{end}}{{if .More}}
+...and {{.More}} more new post(s) that did not fit in this email.
+{{end}}
+To stop receiving these emails, visit {{.UnsubscribeURL}}
+`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+<p>Hi {{.Name}},</p>
+<p>Here is your {{.Frequency}} digest with {{len .Posts}} new post(s).</p>
+<ul>
+{{range .Posts}}<li><a href="{{.Url}}">{{.Title}}</a><br><small>{{.FeedTitle}} - {{.PublishDate.Format "Jan 2, 2006"}}</small></li>
+{{end}}</ul>
+{{if .More}}<p>...and {{.More}} more new post(s) that did not fit in this email.</p>
+{{end}}<p><small><a href="{{.UnsubscribeURL}}">Unsubscribe</a> from these emails.</small></p>
+</body>
+</html>
+`))
+
+func render(user database.User, settings database.DigestSetting, posts []database.GetPostsForDigestRow, more int, unsubscribeURL string) (message, error) {
+	data := templateData{
+		Name:           user.Name,
+		Frequency:      settings.Frequency,
+		Posts:          posts,
+		More:           more,
+		UnsubscribeURL: unsubscribeURL,
+	}
+
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, data); err != nil {
+		return message{}, errors.Wrap(err, "rendering text digest")
+	}
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return message{}, errors.Wrap(err, "rendering html digest")
+	}
+
+	return message{
+		To:             settings.Email,
+		Subject:        "Your " + settings.Frequency + " blog digest",
+		Text:           text.String(),
+		HTML:           html.String(),
+		UnsubscribeURL: unsubscribeURL,
+	}, nil
+}
@@ -0,0 +1,102 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SMTPConfig describes the mail server digests are sent through. Username may
+// be empty for servers without authentication, like a local MailHog.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type message struct {
+	To             string
+	Subject        string
+	Text           string
+	HTML           string
+	UnsubscribeURL string
+}
+
+// build renders msg as a multipart/alternative email with plain text and HTML parts.
+func (m message) build(from string) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	}
+	for _, p := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "creating message part")
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write([]byte(p.content)); err != nil {
+			return nil, errors.Wrap(err, "writing message part")
+		}
+		if err := qp.Close(); err != nil {
+			return nil, errors.Wrap(err, "writing message part")
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, errors.Wrap(err, "closing message")
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", m.To)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	if m.UnsubscribeURL != "" {
+		fmt.Fprintf(&msg, "List-Unsubscribe: <%s>\r\n", m.UnsubscribeURL)
+		fmt.Fprintf(&msg, "List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+	}
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+func (c SMTPConfig) send(m message) error {
+	// From may include a display name, the envelope sender is the bare address
+	from, err := mail.ParseAddress(c.From)
+	if err != nil {
+		return errors.Wrap(err, "parsing SMTP_FROM")
+	}
+	data, err := m.build(from.String())
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if c.Username != "" {
+		auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+	if err := smtp.SendMail(net.JoinHostPort(c.Host, c.Port), auth, from.Address, []string{m.To}, data); err != nil {
+		return errors.Wrap(err, "sending mail")
+	}
+	return nil
+}
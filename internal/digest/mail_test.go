@@ -0,0 +1,108 @@
+package digest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// fakeSMTP accepts one connection on a local port, answers the commands
+// net/smtp sends for an unauthenticated message and returns the message data.
+func fakeSMTP(t *testing.T) (host, port string, data <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	out := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				reply("354 end data with <CR><LF>.<CR><LF>")
+				var msg strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					msg.WriteString(l)
+				}
+				out <- msg.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	host, port, _ = net.SplitHostPort(ln.Addr().String())
+	return host, port, out
+}
+
+func TestSendDigestWithMorePosts(t *testing.T) {
+	host, port, data := fakeSMTP(t)
+	cfg := SMTPConfig{Host: host, Port: port, From: "Digest <digest@example.com>"}
+
+	user := database.User{ID: uuid.New(), Name: "alice"}
+	settings := database.DigestSetting{UserID: user.ID, Email: "alice@example.com", Frequency: FrequencyDaily}
+	posts := []database.GetPostsForDigestRow{
+		{ID: uuid.New(), Title: "First post", Url: "https://example.com/1", PublishDate: time.Now(), FeedTitle: "Example"},
+		{ID: uuid.New(), Title: "Second post", Url: "https://example.com/2", PublishDate: time.Now(), FeedTitle: "Example"},
+	}
+
+	msg, err := render(user, settings, posts, 3, "https://example.com/unsubscribe")
+	if err != nil {
+		t.Fatalf("rendering digest: %v", err)
+	}
+	if err := cfg.send(msg); err != nil {
+		t.Fatalf("sending digest: %v", err)
+	}
+
+	var got string
+	select {
+	case got = <-data:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	for _, want := range []string{
+		"To: alice@example.com",
+		"List-Unsubscribe: <https://example.com/unsubscribe>",
+		"First post",
+		"Second post",
+		"and 3 more new post(s)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("message does not contain %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	PostCreated     Type = "post.created"
+	FeedCreated     Type = "feed.created"
+	FeedFetchFailed Type = "feed.fetch_failed"
+)
+
+// Event is kept small on purpose: subscribers load whatever they need from the
+// database, so the payload stays valid for any transport.
+type Event struct {
+	Type   Type      `json:"type"`
+	FeedID uuid.UUID `json:"feed_id"`
+	PostID uuid.UUID `json:"post_id,omitempty"`
+	Error  string    `json:"error,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// Publisher is the write side of a Bus. The scrapper only depends on this.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// Bus delivers published events to every current subscriber.
+type Bus interface {
+	Publisher
+	// Subscribe returns a channel of events and a function that must be called
+	// to stop the subscription and release the channel.
+	Subscribe() (<-chan Event, func())
+	// Close ends every subscription by closing its channel.
+	Close() error
+}
+
+const subscriberBuffer = 64
+
+// MemoryBus is an in-process Bus. Events only reach subscribers in the same
+// process, and a subscriber that falls more than subscriberBuffer events behind
+// misses events rather than blocking publishers.
+type MemoryBus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]chan Event
+	closed bool
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subs: make(map[int]chan Event)}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, e Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			// slow subscriber, drop the event
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe() (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = ch
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[id]; ok {
+			delete(b.subs, id)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe
+}
+
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for id, ch := range b.subs {
+		delete(b.subs, id)
+		close(ch)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const (
+	notifyChannel = "blog_aggregator_events"
+	// NOTIFY payloads are limited to 8000 bytes
+	maxPayloadSize = 8000
+	listenerPing   = 90 * time.Second
+)
+
+// PostgresBus publishes events with NOTIFY and receives them with LISTEN, so an
+// event published by one server instance reaches subscribers on all of them.
+// Events published while the listener is reconnecting are missed.
+type PostgresBus struct {
+	db       *sql.DB
+	listener *pq.Listener
+	local    *MemoryBus
+	logger   *log.Logger
+	done     chan struct{}
+}
+
+func NewPostgresBus(db *sql.DB, connStr string, logger *log.Logger) (*PostgresBus, error) {
+	listener := pq.NewListener(connStr, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Printf("Event listener error: %v", err)
+		}
+	})
+	if err := listener.Listen(notifyChannel); err != nil {
+		listener.Close()
+		return nil, errors.Wrap(err, "listening on "+notifyChannel)
+	}
+
+	b := &PostgresBus{
+		db:       db,
+		listener: listener,
+		local:    NewMemoryBus(),
+		logger:   logger,
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b, nil
+}
+
+func (b *PostgresBus) Publish(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "encoding event")
+	}
+	if len(payload) > maxPayloadSize {
+		return errors.Errorf("event payload of %d bytes is too large", len(payload))
+	}
+
+	if _, err := b.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", notifyChannel, string(payload)); err != nil {
+		return errors.Wrap(err, "notifying "+notifyChannel)
+	}
+	return nil
+}
+
+func (b *PostgresBus) Subscribe() (<-chan Event, func()) {
+	return b.local.Subscribe()
+}
+
+func (b *PostgresBus) Close() error {
+	close(b.done)
+	err := b.listener.Close()
+	b.local.Close()
+	return err
+}
+
+// run forwards notifications to the local subscribers until Close is called.
+func (b *PostgresBus) run() {
+	ping := time.NewTicker(listenerPing)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case n, ok := <-b.listener.Notify:
+			if !ok {
+				return
+			}
+			if n == nil {
+				// the connection was re-established, notifications in between are lost
+				b.logger.Printf("Event listener reconnected to %s", notifyChannel)
+				continue
+			}
+			var e Event
+			if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+				b.logger.Printf("Failed to decode event %q: %v", n.Extra, err)
+				continue
+			}
+			if err := b.local.Publish(context.Background(), e); err != nil {
+				b.logger.Printf("Failed to deliver event %s: %v", e.Type, err)
+			}
+		case <-ping.C:
+			go func() {
+				if err := b.listener.Ping(); err != nil {
+					b.logger.Printf("Event listener ping failed: %v", err)
+				}
+			}()
+		}
+	}
+}
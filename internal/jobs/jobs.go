@@ -0,0 +1,194 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	// KindRefresh scrapes a feed right away instead of waiting for the scrapper ticker.
+	KindRefresh = "refresh"
+	// KindCreateFeed validates a newly added feed and stores its first posts.
+	KindCreateFeed = "create_feed"
+)
+
+const (
+	// queued jobs of other server instances are picked up at least this often
+	pollInterval = 5 * time.Second
+	// running jobs older than this were abandoned by a stopped instance
+	staleAfter  = 10 * time.Minute
+	jobTimeout  = 2 * time.Minute
+	maxErrorLen = 1000
+)
+
+// Handler runs a job and returns the number of new posts it stored.
+type Handler func(ctx context.Context, job database.Job) (int, error)
+
+// Queue runs jobs stored in the jobs table with a pool of workers. Jobs are
+// claimed with FOR UPDATE SKIP LOCKED, so every server instance can run one.
+type Queue struct {
+	db       *database.Queries
+	logger   *log.Logger
+	workers  int
+	handlers map[string]Handler
+	// wake lets Enqueue start a job without waiting for the next poll
+	wake chan struct{}
+}
+
+func NewQueue(db *database.Queries, workers int, logger *log.Logger) *Queue {
+	return &Queue{
+		db:       db,
+		logger:   logger,
+		workers:  workers,
+		handlers: make(map[string]Handler),
+		wake:     make(chan struct{}, workers),
+	}
+}
+
+// Handle registers the handler for a job kind. It must be called before Run.
+func (q *Queue) Handle(kind string, h Handler) {
+	q.handlers[kind] = h
+}
+
+// Enqueue stores a queued job of kind for the feed.
+func (q *Queue) Enqueue(ctx context.Context, kind string, userID, feedID uuid.UUID) (database.Job, error) {
+	job, err := q.db.CreateJob(ctx, database.CreateJobParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    userID,
+		FeedID:    feedID,
+		Kind:      kind,
+	})
+	if err != nil {
+		return database.Job{}, errors.Wrap(err, "creating job")
+	}
+
+	q.Wake()
+	return job, nil
+}
+
+// Wake starts a queued job without waiting for the next poll. Jobs created in a
+// transaction call it after the commit.
+func (q *Queue) Wake() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+		// every worker is already awake
+	}
+}
+
+// Run starts the workers and blocks until ctx is done and they have returned.
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(staleAfter / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+			now := sql.NullTime{Time: time.Now(), Valid: true}
+			failed, err := q.db.FailStaleJobs(ctx, database.FailStaleJobsParams{
+				Now:           now,
+				StartedBefore: sql.NullTime{Time: now.Time.Add(-staleAfter), Valid: true},
+			})
+			if err != nil {
+				q.logger.Printf("Failed to fail stale jobs: %+v", err)
+			} else if failed > 0 {
+				q.logger.Printf("Marked %d interrupted jobs as failed", failed)
+			}
+		}
+	}
+}
+
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// run jobs until the queue is empty, then wait
+		for ctx.Err() == nil {
+			job, err := q.db.ClaimNextJob(ctx, sql.NullTime{Time: time.Now(), Valid: true})
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			if err != nil {
+				q.logger.Printf("Failed to claim job: %+v", err)
+				break
+			}
+			q.run(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) run(ctx context.Context, job database.Job) {
+	newPosts, runErr := q.call(ctx, job)
+
+	params := database.FinishJobParams{
+		ID:         job.ID,
+		Status:     StatusSucceeded,
+		NewPosts:   sql.NullInt32{Int32: int32(newPosts), Valid: true},
+		FinishedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+	if runErr != nil {
+		msg := runErr.Error()
+		if len(msg) > maxErrorLen {
+			msg = msg[:maxErrorLen]
+		}
+		params.Status = StatusFailed
+		params.Error = sql.NullString{String: msg, Valid: true}
+	}
+
+	// record the outcome even when shutting down
+	if _, err := q.db.FinishJob(context.WithoutCancel(ctx), params); err != nil {
+		q.logger.Printf("Failed to finish job %v: %+v", job.ID, err)
+	}
+}
+
+func (q *Queue) call(ctx context.Context, job database.Job) (newPosts int, err error) {
+	h, ok := q.handlers[job.Kind]
+	if !ok {
+		return 0, errors.Errorf("no handler for job kind %q", job.Kind)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+	return h(ctx, job)
+}
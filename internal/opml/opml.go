@@ -0,0 +1,123 @@
+package opml
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html/charset"
+)
+
+type Opml struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title string `xml:"title"`
+	} `xml:"head"`
+	Body struct {
+		Outlines []Outline `xml:"outline"`
+	} `xml:"body"`
+}
+
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Subscription is a single feed found in an OPML document.
+type Subscription struct {
+	Title   string
+	XMLURL  string
+	HTMLURL string
+	// Folder is the path of the enclosing outlines joined with " / ",
+	// or empty for feeds at the top level.
+	Folder string
+}
+
+func (o Outline) name() string {
+	if o.Title != "" {
+		return o.Title
+	}
+	return o.Text
+}
+
+// Parse reads an OPML document and flattens its outlines into subscriptions.
+func Parse(r io.Reader) ([]Subscription, error) {
+	doc := &Opml{}
+	decoder := xml.NewDecoder(r)
+	// Go only decodes UTF-8, exports in other declared encodings like ISO-8859-1
+	// are converted
+	decoder.CharsetReader = charset.NewReaderLabel
+	if err := decoder.Decode(doc); err != nil {
+		return nil, errors.Wrap(err, "parsing opml failed")
+	}
+
+	var subs []Subscription
+	var walk func(outlines []Outline, path []string)
+	walk = func(outlines []Outline, path []string) {
+		for _, o := range outlines {
+			if o.XMLURL != "" {
+				subs = append(subs, Subscription{
+					Title:   strings.TrimSpace(o.name()),
+					XMLURL:  strings.TrimSpace(o.XMLURL),
+					HTMLURL: strings.TrimSpace(o.HTMLURL),
+					Folder:  strings.Join(path, " / "),
+				})
+				continue
+			}
+			name := strings.TrimSpace(o.name())
+			if name == "" {
+				walk(o.Outlines, path)
+				continue
+			}
+			walk(o.Outlines, append(path[:len(path):len(path)], name))
+		}
+	}
+	walk(doc.Body.Outlines, nil)
+
+	return subs, nil
+}
+
+// Render writes subscriptions as an OPML 2.0 document. Subscriptions sharing a
+// folder are grouped under one outline named after it.
+func Render(w io.Writer, title string, subs []Subscription) error {
+	doc := Opml{Version: "2.0"}
+	doc.Head.Title = title
+
+	folderIndex := make(map[string]int)
+	for _, sub := range subs {
+		outline := Outline{
+			Text:    sub.Title,
+			Title:   sub.Title,
+			Type:    "rss",
+			XMLURL:  sub.XMLURL,
+			HTMLURL: sub.HTMLURL,
+		}
+		if sub.Folder == "" {
+			doc.Body.Outlines = append(doc.Body.Outlines, outline)
+			continue
+		}
+		i, ok := folderIndex[sub.Folder]
+		if !ok {
+			doc.Body.Outlines = append(doc.Body.Outlines, Outline{Text: sub.Folder, Title: sub.Folder})
+			i = len(doc.Body.Outlines) - 1
+			folderIndex[sub.Folder] = i
+		}
+		doc.Body.Outlines[i].Outlines = append(doc.Body.Outlines[i].Outlines, outline)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.Wrap(err, "writing opml failed")
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return errors.Wrap(err, "encoding opml failed")
+	}
+	return nil
+}
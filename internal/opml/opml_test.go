@@ -0,0 +1,36 @@
+package opml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDeclaredCharset(t *testing.T) {
+	// "Café" and "Älteres" encoded as ISO-8859-1
+	doc := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		"<opml version=\"2.0\"><body>" +
+		"<outline text=\"\xc4lteres\">" +
+		"<outline text=\"Caf\xe9\" xmlUrl=\"https://example.com/feed.xml\"/>" +
+		"</outline></body></opml>"
+
+	subs, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(subs) != 1 {
+		t.Fatalf("got %d subscriptions, want 1", len(subs))
+	}
+	if subs[0].Title != "Café" || subs[0].Folder != "Älteres" {
+		t.Errorf("got title %q in folder %q, want \"Café\" in \"Älteres\"", subs[0].Title, subs[0].Folder)
+	}
+}
+
+func TestParseRejectsInvalidUTF8(t *testing.T) {
+	doc := "<opml version=\"2.0\"><body>" +
+		"<outline text=\"Caf\xe9\" xmlUrl=\"https://example.com/feed.xml\"/>" +
+		"</body></opml>"
+
+	if _, err := Parse(strings.NewReader(doc)); err == nil {
+		t.Error("Parse accepted a document that is not valid UTF-8 and declares no encoding")
+	}
+}
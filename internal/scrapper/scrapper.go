@@ -13,48 +13,145 @@ import (
 	"time"
 
 	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/events"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
 
 type Rss struct {
 	Channel struct {
-		Title         string `xml:"title"`
-		Link          string `xml:"link"`
-		Description   string `xml:"description"`
-		LastBuildDate string `xml:"lastBuildDate"`
+		Title string `xml:"title"`
+		// the plain RSS link and any atom:link elements, see link and atomLink
+		Links         []rssLink `xml:"link"`
+		Description   string    `xml:"description"`
+		LastBuildDate string    `xml:"lastBuildDate"`
 		Items         []struct {
 			Title       string `xml:"title"`
 			Link        string `xml:"link"`
 			PubDate     string `xml:"pubDate"`
 			Description string `xml:"description"`
+			Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
 		} `xml:"item"`
 	} `xml:"channel"`
 }
 
+type rssLink struct {
+	Href  string `xml:"href,attr"`
+	Rel   string `xml:"rel,attr"`
+	Value string `xml:",chardata"`
+}
+
+// link returns the website of the channel.
+func (r *Rss) link() string {
+	for _, l := range r.Channel.Links {
+		if l.Href == "" && strings.TrimSpace(l.Value) != "" {
+			return strings.TrimSpace(l.Value)
+		}
+	}
+	return ""
+}
+
+// atomLink returns the href of the first atom:link with the given rel.
+func (r *Rss) atomLink(rel string) string {
+	for _, l := range r.Channel.Links {
+		if l.Href != "" && l.Rel == rel {
+			return l.Href
+		}
+	}
+	return ""
+}
+
 type FeedInfo struct {
 	Title       string
 	Description string
+	Link        string
+	// Hub and Self are the WebSub hub and topic the feed advertises, if any.
+	Hub  string
+	Self string
+}
+
+// Result describes the outcome of a scrape or of content pushed by a WebSub hub.
+type Result struct {
+	NewPosts int
+	Hub      string
+	Self     string
 }
 
-func ScrapeFeed(ctx context.Context, db *database.Queries, feed database.Feed) error {
-	// Scrape the feed
-	// Save the feed to the database
+// maxEventErrorLength keeps feed.fetch_failed events well under NOTIFY's payload limit
+const maxEventErrorLength = 500
+
+// ScrapeFeed fetches a feed and stores its new posts. A failure is published
+// as a feed.fetch_failed event.
+func ScrapeFeed(ctx context.Context, db *database.Queries, pub events.Publisher, feed database.Feed) (Result, error) {
 	fmt.Println("Scraping feed", feed.Url)
 
-	body, err := fetchURL(feed.Url)
+	body, err := FetchFeed(ctx, feed.Url)
+	if err != nil {
+		publishFetchFailed(ctx, pub, feed, err)
+		return Result{}, err
+	}
+
+	feedData, err := parseRss(feed, body)
+	if err == nil && len(feedData.Channel.Items) == 0 {
+		// a polled feed without items is broken, unlike a WebSub push that may carry none
+		err = errors.New("no items in feed " + feed.Url)
+	}
 	if err != nil {
-		return errors.Wrap(err, "fetching feed failed for "+feed.Url)
+		publishFetchFailed(ctx, pub, feed, err)
+		return Result{}, err
 	}
 
+	result, err := ingest(ctx, db, pub, feed, feedData)
+	if err != nil {
+		publishFetchFailed(ctx, pub, feed, err)
+		return result, err
+	}
+	log.Println("Scraped feed", feed.Url, "with", result.NewPosts, "new posts")
+
+	return result, nil
+}
+
+func publishFetchFailed(ctx context.Context, pub events.Publisher, feed database.Feed, fetchErr error) {
+	msg := fetchErr.Error()
+	if len(msg) > maxEventErrorLength {
+		msg = msg[:maxEventErrorLength]
+	}
+	if err := pub.Publish(ctx, events.Event{
+		Type:   events.FeedFetchFailed,
+		FeedID: feed.ID,
+		Error:  msg,
+		At:     time.Now(),
+	}); err != nil {
+		log.Printf("Failed to publish %s for %v: %+v", events.FeedFetchFailed, feed.ID, err)
+	}
+}
+
+// IngestFeed parses an RSS document for feed, whether fetched or pushed by a
+// WebSub hub, and stores the posts that are not known yet.
+func IngestFeed(ctx context.Context, db *database.Queries, pub events.Publisher, feed database.Feed, body []byte) (Result, error) {
+	feedData, err := parseRss(feed, body)
+	if err != nil {
+		return Result{}, err
+	}
+	return ingest(ctx, db, pub, feed, feedData)
+}
+
+func parseRss(feed database.Feed, body []byte) (*Rss, error) {
 	feedData := &Rss{}
+	if err := xml.Unmarshal(body, &feedData); err != nil {
+		return nil, errors.Wrap(err, "parsing feed failed for "+feed.Url)
+	}
+	return feedData, nil
+}
 
-	if err := xml.Unmarshal(body, &feedData); err != nil || len(feedData.Channel.Items) == 0 {
-		return errors.Wrap(err, "parsing feed failed for "+feed.Url)
+func ingest(ctx context.Context, db *database.Queries, pub events.Publisher, feed database.Feed, feedData *Rss) (Result, error) {
+	result := Result{
+		Hub:  feedData.atomLink("hub"),
+		Self: feedData.atomLink("self"),
 	}
 
 	// update the last fetched at time
-	_, err = db.MarkFeedAsFetched(ctx, database.MarkFeedAsFetchedParams{
+	_, err := db.MarkFeedAsFetched(ctx, database.MarkFeedAsFetchedParams{
 		ID: feed.ID,
 		LastFetchedAt: sql.NullTime{
 			Time:  time.Now(),
@@ -63,7 +160,7 @@ func ScrapeFeed(ctx context.Context, db *database.Queries, feed database.Feed) e
 		UpdatedAt: time.Now(),
 	})
 	if err != nil {
-		return errors.Wrap(err, "updating feed to the database for "+feed.Url)
+		return result, errors.Wrap(err, "updating feed to the database for "+feed.Url)
 	}
 
 	for _, item := range feedData.Channel.Items {
@@ -74,10 +171,10 @@ func ScrapeFeed(ctx context.Context, db *database.Queries, feed database.Feed) e
 		}
 		parsedTime, err := parseTime(item.PubDate)
 		if err != nil {
-			return errors.Wrap(err, "parsing published time failed for "+feed.Url)
+			return result, errors.Wrap(err, "parsing published time failed for "+feed.Url)
 		}
 
-		_, err = db.CreatePost(ctx, database.CreatePostParams{
+		post, err := db.CreatePost(ctx, database.CreatePostParams{
 			ID:          uuid.New(),
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
@@ -86,22 +183,45 @@ func ScrapeFeed(ctx context.Context, db *database.Queries, feed database.Feed) e
 			Url:         item.Link,
 			Description: item.Description,
 			PublishDate: parsedTime,
+			Content:     item.Content,
 		})
 		if err != nil {
-			return errors.Wrap(err, "creating post to the database for "+feed.Url)
+			return result, errors.Wrap(err, "creating post to the database for "+feed.Url)
+		}
+		result.NewPosts++
+
+		if err := pub.Publish(ctx, events.Event{
+			Type:   events.PostCreated,
+			FeedID: feed.ID,
+			PostID: post.ID,
+			At:     post.CreatedAt,
+		}); err != nil {
+			log.Printf("Failed to publish %s for %v: %+v", events.PostCreated, post.ID, err)
 		}
 	}
-	log.Println("Scraped feed", feed.Url, "with", len(feedData.Channel.Items), "items")
 
-	return nil
+	return result, nil
 }
 
 func FetchFeedInfo(ctx context.Context, url string) (*FeedInfo, error) {
-	body, err := fetchURL(url)
+	body, err := fetchURL(ctx, url)
 	if err != nil {
 		return nil, errors.Wrap(err, "fetching feed info failed for "+url)
 	}
+	return ParseFeedInfo(url, body)
+}
+
+// FetchFeed returns the document at url, so a new feed can be validated with
+// ParseFeedInfo and ingested with IngestFeed from a single fetch.
+func FetchFeed(ctx context.Context, url string) ([]byte, error) {
+	body, err := fetchURL(ctx, url)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching feed failed for "+url)
+	}
+	return body, nil
+}
 
+func ParseFeedInfo(url string, body []byte) (*FeedInfo, error) {
 	feedData := &Rss{}
 	if err := xml.Unmarshal(body, &feedData); err != nil {
 		return nil, errors.Wrap(err, "parsing feed info failed for "+url)
@@ -110,11 +230,16 @@ func FetchFeedInfo(ctx context.Context, url string) (*FeedInfo, error) {
 	feedInfo := &FeedInfo{
 		Title:       feedData.Channel.Title,
 		Description: feedData.Channel.Description,
+		Link:        feedData.link(),
+		Hub:         feedData.atomLink("hub"),
+		Self:        feedData.atomLink("self"),
 	}
 	return feedInfo, nil
 }
 
-func fetchURL(inputUrl string) ([]byte, error) {
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+func fetchURL(ctx context.Context, inputUrl string) ([]byte, error) {
 
 	// parse the url to check if it is valid
 	parsedURL, err := url.ParseRequestURI(inputUrl)
@@ -127,7 +252,12 @@ func fetchURL(inputUrl string) ([]byte, error) {
 		return nil, errors.New("Only HTTP and HTTPS protocols are supported")
 	}
 
-	resp, err := http.Get(parsedURL.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsedURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
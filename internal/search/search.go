@@ -0,0 +1,112 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// ParseQuery turns a user search string into a Postgres to_tsquery expression.
+//
+// Supported syntax:
+//   - words are ANDed together: go generics
+//   - "quoted phrases" match words next to each other: "error handling"
+//   - a trailing * matches prefixes: concurr*
+//   - a leading - excludes a term: golang -rust
+func ParseQuery(q string) (string, error) {
+	var terms []string
+
+	for _, tok := range tokenize(q) {
+		negate := false
+		if !tok.phrase && strings.HasPrefix(tok.text, "-") {
+			negate = true
+			tok.text = strings.TrimLeft(tok.text, "-")
+		}
+
+		term := phraseTerm(tok.text)
+		if term == "" {
+			continue
+		}
+		if negate {
+			term = "!(" + term + ")"
+		}
+		terms = append(terms, term)
+	}
+
+	if len(terms) == 0 {
+		return "", errors.New("search query has no searchable words")
+	}
+	return strings.Join(terms, " & "), nil
+}
+
+type token struct {
+	text   string
+	phrase bool
+}
+
+// tokenize splits on whitespace, keeping double-quoted phrases together.
+func tokenize(q string) []token {
+	var tokens []token
+	var cur strings.Builder
+	inQuote := false
+
+	flush := func(phrase bool) {
+		if cur.Len() > 0 {
+			tokens = append(tokens, token{text: cur.String(), phrase: phrase})
+			cur.Reset()
+		}
+	}
+
+	for _, r := range q {
+		switch {
+		case r == '"':
+			flush(inQuote)
+			inQuote = !inQuote
+		case unicode.IsSpace(r) && !inQuote:
+			flush(false)
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush(inQuote)
+
+	return tokens
+}
+
+// phraseTerm joins the words of s with the followed-by operator. Words ending in
+// * become prefix matches. Anything that is not a letter or digit separates words,
+// so user input can never inject tsquery operators.
+func phraseTerm(s string) string {
+	var lexemes []string
+	for _, field := range strings.Fields(s) {
+		prefix := strings.HasSuffix(field, "*")
+		words := strings.FieldsFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for i, word := range words {
+			lexeme := strings.ToLower(word)
+			if prefix && i == len(words)-1 {
+				lexeme += ":*"
+			}
+			lexemes = append(lexemes, lexeme)
+		}
+	}
+	return strings.Join(lexemes, " <-> ")
+}
+
+// Delimiters SearchPostsByUser puts around matches in its snippets.
+const (
+	snippetStart = "\x02"
+	snippetStop  = "\x03"
+)
+
+// Snippet turns a snippet from SearchPostsByUser into HTML that is safe to render:
+// the text is escaped and matches are wrapped in <mark>. Feed content is
+// untrusted, so any markup that survived stripping tags is shown as text.
+func Snippet(raw string) string {
+	escaped := html.EscapeString(html.UnescapeString(raw))
+	escaped = strings.ReplaceAll(escaped, snippetStart, "<mark>")
+	return strings.ReplaceAll(escaped, snippetStop, "</mark>")
+}
@@ -0,0 +1,20 @@
+package search
+
+import "testing"
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"plain \x02match\x03 text", "plain <mark>match</mark> text"},
+		{"a < b \x02go\x03", "a &lt; b <mark>go</mark>"},
+		{"<img src=x onerror=alert(1) \x02go\x03", "&lt;img src=x onerror=alert(1) <mark>go</mark>"},
+		{"&lt;script&gt; \x02go\x03 &amp; more", "&lt;script&gt; <mark>go</mark> &amp; more"},
+	}
+	for _, tt := range tests {
+		if got := Snippet(tt.raw); got != tt.want {
+			t.Errorf("Snippet(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddr(netip.MustParseAddr(tt.addr)); got != tt.public {
+			t.Errorf("publicAddr(%v) = %v, want %v", tt.addr, got, tt.public)
+		}
+	}
+}
+
+func TestCheckHostRejectsLoopback(t *testing.T) {
+	for _, host := range []string{"localhost", "127.0.0.1", "::1"} {
+		if err := CheckHost(context.Background(), host); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("CheckHost(%q) = %v, want ErrPrivateAddress", host, err)
+		}
+	}
+}
+
+func TestDialPublicOnly(t *testing.T) {
+	if err := dialPublicOnly("tcp", "127.0.0.1:80", nil); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("dialing loopback: got %v, want ErrPrivateAddress", err)
+	}
+	if err := dialPublicOnly("tcp", "[2606:2800:220:1:248:1893:25c8:1946]:443", nil); err != nil {
+		t.Errorf("dialing a public address: %v", err)
+	}
+}
@@ -0,0 +1,393 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/events"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+
+	// EventTest is sent by the test endpoint so receivers can tell it apart.
+	EventTest = "webhook.test"
+)
+
+const (
+	deliveryTimeout = 10 * time.Second
+	// a claimed delivery is retried by any instance once its lease runs out
+	leaseDuration = 2 * time.Minute
+	pollInterval  = 5 * time.Second
+	batchSize     = 20
+	maxAttempts   = 8
+	baseBackoff   = 30 * time.Second
+	maxBackoff    = 6 * time.Hour
+	maxErrorLen   = 1000
+
+	sweepInterval = 30 * time.Second
+	// posts are swept once they are this old, so a post written with a slightly
+	// earlier clock by another instance is not passed by the high-water mark
+	sweepLag   = time.Minute
+	sweepBatch = 100
+)
+
+// Signature headers sent with every delivery. The signature is the hex HMAC-SHA256
+// of "<timestamp>.<body>" keyed with the webhook secret.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+type FeedPayload struct {
+	ID    uuid.UUID `json:"id"`
+	Title string    `json:"title"`
+	Url   string    `json:"url"`
+}
+
+// Payload is the JSON body of a delivery. It is stored with the delivery so
+// every retry sends exactly the same bytes.
+type Payload struct {
+	Event     string         `json:"event"`
+	WebhookID uuid.UUID      `json:"webhook_id"`
+	CreatedAt time.Time      `json:"created_at"`
+	Feed      *FeedPayload   `json:"feed,omitempty"`
+	Post      *database.Post `json:"post,omitempty"`
+}
+
+// Sign returns the value of the signature header for body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher queues a delivery for every matching webhook when a post is
+// created and sends queued deliveries, retrying failures with exponential backoff.
+type Dispatcher struct {
+	db     *database.Queries
+	client *http.Client
+	logger *log.Logger
+}
+
+func NewDispatcher(db *database.Queries, logger *log.Logger) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		client: NewPublicClient(deliveryTimeout),
+		logger: logger,
+	}
+}
+
+// Run queues deliveries for post.created events from bus and sends due deliveries
+// until ctx is done. The queue lives in the database, so deliveries survive
+// restarts and are shared between server instances. Events can be dropped or
+// lost across restarts, so a sweep also queues every post past a high-water
+// mark kept in the database.
+func (d *Dispatcher) Run(ctx context.Context, bus events.Bus) {
+	ch, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	// slow receivers must not hold up reading events, the bus drops events for
+	// subscribers that fall behind
+	go d.deliverLoop(ctx)
+	go d.sweepLoop(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if e.Type != events.PostCreated {
+				continue
+			}
+			if err := d.enqueuePost(ctx, e.PostID); err != nil {
+				d.logger.Printf("Failed to queue webhooks for post %v: %+v", e.PostID, err)
+			}
+		}
+	}
+}
+
+// deliverLoop sends due deliveries every pollInterval until ctx is done.
+func (d *Dispatcher) deliverLoop(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.deliverDue(ctx)
+		}
+	}
+}
+
+// sweepLoop queues the posts the event path missed every sweepInterval until ctx is done.
+func (d *Dispatcher) sweepLoop(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.sweep(ctx); err != nil {
+				d.logger.Printf("Failed to sweep posts for webhooks: %+v", err)
+			}
+		}
+	}
+}
+
+// sweep queues deliveries for the posts created after the high-water mark and
+// moves the mark past them. Posts already queued from their event are skipped
+// by the unique (webhook_id, post_id).
+func (d *Dispatcher) sweep(ctx context.Context) error {
+	mark, err := d.db.GetWebhookSweep(ctx)
+	if err != nil {
+		return errors.Wrap(err, "getting high-water mark")
+	}
+
+	before := time.Now().Add(-sweepLag)
+	for {
+		posts, err := d.db.GetPostsCreatedAfter(ctx, database.GetPostsCreatedAfterParams{
+			AfterDate: mark.SweptUntil,
+			AfterID:   mark.LastPostID,
+			Before:    before,
+			PageLimit: sweepBatch,
+		})
+		if err != nil {
+			return errors.Wrap(err, "getting posts")
+		}
+		if len(posts) == 0 {
+			return nil
+		}
+
+		for _, post := range posts {
+			if err := d.enqueue(ctx, post); err != nil {
+				return errors.Wrapf(err, "queueing webhooks for post %v", post.ID)
+			}
+		}
+
+		last := posts[len(posts)-1]
+		mark.SweptUntil, mark.LastPostID = last.CreatedAt, last.ID
+		if err := d.db.AdvanceWebhookSweep(ctx, database.AdvanceWebhookSweepParams{
+			SweptUntil: mark.SweptUntil,
+			LastPostID: mark.LastPostID,
+		}); err != nil {
+			return errors.Wrap(err, "moving high-water mark")
+		}
+		if len(posts) < sweepBatch {
+			return nil
+		}
+	}
+}
+
+func (d *Dispatcher) enqueuePost(ctx context.Context, postID uuid.UUID) error {
+	post, err := d.db.GetPostByID(ctx, postID)
+	if err != nil {
+		return errors.Wrap(err, "getting post")
+	}
+	return d.enqueue(ctx, post)
+}
+
+// enqueue queues a delivery of post for every webhook it matches.
+func (d *Dispatcher) enqueue(ctx context.Context, post database.Post) error {
+	webhooks, err := d.db.GetWebhooksForPost(ctx, post.ID)
+	if err != nil {
+		return errors.Wrap(err, "getting webhooks")
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	feed, err := d.db.GetFeedByID(ctx, post.FeedID)
+	if err != nil {
+		return errors.Wrap(err, "getting feed")
+	}
+
+	for _, wh := range webhooks {
+		payload, err := json.Marshal(Payload{
+			Event:     string(events.PostCreated),
+			WebhookID: wh.ID,
+			CreatedAt: time.Now(),
+			Feed:      &FeedPayload{ID: feed.ID, Title: feed.Title, Url: feed.Url},
+			Post:      &post,
+		})
+		if err != nil {
+			return errors.Wrap(err, "encoding payload")
+		}
+
+		// every instance sees the event, the unique (webhook_id, post_id) keeps one delivery
+		_, err = d.db.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			ID:            uuid.New(),
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+			WebhookID:     wh.ID,
+			PostID:        uuid.NullUUID{UUID: post.ID, Valid: true},
+			Event:         string(events.PostCreated),
+			Payload:       payload,
+			NextAttemptAt: time.Now(),
+		})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return errors.Wrapf(err, "queueing delivery for webhook %v", wh.ID)
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	now := time.Now()
+	deliveries, err := d.db.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+		LeaseUntil: now.Add(leaseDuration),
+		Now:        now,
+		BatchSize:  batchSize,
+	})
+	if err != nil {
+		d.logger.Printf("Failed to claim webhook deliveries: %+v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery database.WebhookDelivery) {
+			defer wg.Done()
+
+			wh, err := d.db.GetWebhook(ctx, delivery.WebhookID)
+			if err != nil {
+				d.logger.Printf("Failed to get webhook %v: %+v", delivery.WebhookID, err)
+				return
+			}
+			if _, err := d.Deliver(ctx, wh, delivery); err != nil {
+				d.logger.Printf("Failed to record webhook delivery %v: %+v", delivery.ID, err)
+			}
+		}(delivery)
+	}
+	wg.Wait()
+}
+
+// SendTest queues a test payload for wh and sends it right away. The returned
+// delivery holds the outcome; a failed test is retried like any other delivery.
+func (d *Dispatcher) SendTest(ctx context.Context, wh database.Webhook) (database.WebhookDelivery, error) {
+	payload, err := json.Marshal(Payload{
+		Event:     EventTest,
+		WebhookID: wh.ID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return database.WebhookDelivery{}, errors.Wrap(err, "encoding payload")
+	}
+
+	delivery, err := d.db.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		WebhookID: wh.ID,
+		Event:     EventTest,
+		Payload:   payload,
+		// leased so the worker does not pick it up while we send it
+		NextAttemptAt: time.Now().Add(leaseDuration),
+	})
+	if err != nil {
+		return database.WebhookDelivery{}, errors.Wrap(err, "queueing test delivery")
+	}
+
+	return d.Deliver(ctx, wh, delivery)
+}
+
+// Deliver sends a single attempt of delivery and records its outcome.
+func (d *Dispatcher) Deliver(ctx context.Context, wh database.Webhook, delivery database.WebhookDelivery) (database.WebhookDelivery, error) {
+	code, sendErr := d.send(ctx, wh, delivery)
+
+	now := time.Now()
+	params := database.RecordWebhookDeliveryAttemptParams{
+		ID:            delivery.ID,
+		Status:        StatusSucceeded,
+		NextAttemptAt: now,
+		LastAttemptAt: sql.NullTime{Time: now, Valid: true},
+		UpdatedAt:     now,
+	}
+	if code != 0 {
+		params.ResponseCode = sql.NullInt32{Int32: int32(code), Valid: true}
+	}
+	if sendErr != nil {
+		msg := sendErr.Error()
+		if len(msg) > maxErrorLen {
+			msg = msg[:maxErrorLen]
+		}
+		params.LastError = sql.NullString{String: msg, Valid: true}
+
+		attempts := int(delivery.Attempts) + 1
+		if attempts >= maxAttempts {
+			params.Status = StatusFailed
+		} else {
+			params.Status = StatusPending
+			params.NextAttemptAt = now.Add(backoff(attempts))
+		}
+	}
+
+	// the request context may already be gone, the outcome must still be recorded
+	return d.db.RecordWebhookDeliveryAttempt(context.WithoutCancel(ctx), params)
+}
+
+// send posts the payload and returns the response status code, if any.
+func (d *Dispatcher) send(ctx context.Context, wh database.Webhook, delivery database.WebhookDelivery) (int, error) {
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "blog-aggregator-webhooks")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(wh.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "sending request")
+	}
+	defer resp.Body.Close()
+	// drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the wait before the next attempt after the given number of attempts.
+func backoff(attempts int) time.Duration {
+	wait := baseBackoff << (attempts - 1)
+	if wait > maxBackoff || wait <= 0 {
+		return maxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,224 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/webhook"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	StatusPending = "pending"
+	StatusActive  = "active"
+	StatusDenied  = "denied"
+)
+
+const (
+	// LeaseSeconds is the lease we ask hubs for, they may grant a different one.
+	LeaseSeconds = 10 * 24 * 60 * 60
+
+	renewInterval = time.Hour
+	// leases are renewed this long before they expire
+	renewAhead = 24 * time.Hour
+	// a subscription the hub never verified is requested again after this long
+	pendingRetry   = 24 * time.Hour
+	requestTimeout = 30 * time.Second
+	maxErrorLen    = 1000
+)
+
+// Subscriber subscribes feeds that advertise a hub and keeps their leases renewed.
+// Hubs call back on CallbackURL, which is served by the API.
+type Subscriber struct {
+	db     *database.Queries
+	client *http.Client
+	logger *log.Logger
+	// callbackBase is the public URL the feed id is appended to
+	callbackBase string
+}
+
+func NewSubscriber(db *database.Queries, publicURL string, logger *log.Logger) *Subscriber {
+	return &Subscriber{
+		db: db,
+		// hubs come from the fetched feeds, which may point them at internal addresses
+		client:       webhook.NewPublicClient(requestTimeout),
+		logger:       logger,
+		callbackBase: publicURL + "/v1/websub/",
+	}
+}
+
+func (s *Subscriber) CallbackURL(feedID uuid.UUID) string {
+	return s.callbackBase + feedID.String()
+}
+
+// Ensure subscribes feed to hub unless it is already subscribed to it. topic is
+// the feed's self link, or its URL when it has none.
+func (s *Subscriber) Ensure(ctx context.Context, feed database.Feed, hub, topic string) error {
+	if topic == "" {
+		topic = feed.Url
+	}
+
+	sub, err := s.db.GetWebsubSubscription(ctx, feed.ID)
+	if err == nil && sub.HubUrl == hub && sub.TopicUrl == topic {
+		// renewals and retries are left to Run
+		return nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return errors.Wrap(err, "getting subscription")
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return err
+	}
+	return s.subscribe(ctx, feed.ID, hub, topic, secret)
+}
+
+// Run renews subscriptions before their lease expires until ctx is done.
+func (s *Subscriber) Run(ctx context.Context) {
+	ticker := time.NewTicker(renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.renew(ctx)
+		}
+	}
+}
+
+func (s *Subscriber) renew(ctx context.Context) {
+	now := time.Now()
+	subs, err := s.db.GetWebsubSubscriptionsToRenew(ctx, database.GetWebsubSubscriptionsToRenewParams{
+		RenewBefore: sql.NullTime{Time: now.Add(renewAhead), Valid: true},
+		StaleBefore: now.Add(-pendingRetry),
+	})
+	if err != nil {
+		s.logger.Printf("Failed to get WebSub subscriptions to renew: %+v", err)
+		return
+	}
+
+	for _, sub := range subs {
+		// keep the secret, content signed with it may still be on its way
+		if err := s.subscribe(ctx, sub.FeedID, sub.HubUrl, sub.TopicUrl, sub.Secret); err != nil {
+			s.logger.Printf("Failed to renew WebSub subscription for feed %v: %+v", sub.FeedID, err)
+		}
+	}
+}
+
+// subscribe records the subscription and asks the hub for it. The hub confirms
+// asynchronously by calling the callback URL.
+func (s *Subscriber) subscribe(ctx context.Context, feedID uuid.UUID, hub, topic, secret string) error {
+	if _, err := s.db.UpsertWebsubSubscription(ctx, database.UpsertWebsubSubscriptionParams{
+		FeedID:    feedID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		HubUrl:    hub,
+		TopicUrl:  topic,
+		Secret:    secret,
+	}); err != nil {
+		return errors.Wrap(err, "saving subscription")
+	}
+
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {topic},
+		"hub.callback":      {s.CallbackURL(feedID)},
+		"hub.secret":        {secret},
+		"hub.lease_seconds": {strconv.Itoa(LeaseSeconds)},
+	}
+	reqErr := s.post(ctx, hub, form)
+	if reqErr == nil {
+		return nil
+	}
+
+	msg := reqErr.Error()
+	if len(msg) > maxErrorLen {
+		msg = msg[:maxErrorLen]
+	}
+	if err := s.db.SetWebsubSubscriptionError(ctx, database.SetWebsubSubscriptionErrorParams{
+		FeedID:    feedID,
+		LastError: sql.NullString{String: msg, Valid: true},
+		UpdatedAt: time.Now(),
+	}); err != nil {
+		s.logger.Printf("Failed to record WebSub error for feed %v: %+v", feedID, err)
+	}
+	return reqErr
+}
+
+func (s *Subscriber) post(ctx context.Context, hub string, form url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.Wrap(err, "creating hub request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "sending hub request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.Errorf("hub %s answered %s: %s", hub, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// VerifySignature checks an X-Hub-Signature header ("sha256=<hex>") against
+// the HMAC of body keyed with secret.
+func VerifySignature(secret, header string, body []byte) bool {
+	method, signature, ok := strings.Cut(header, "=")
+	if !ok {
+		return false
+	}
+
+	var h func() hash.Hash
+	switch method {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha384":
+		h = sha512.New384
+	case "sha512":
+		h = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generating secret")
+	}
+	return hex.EncodeToString(b), nil
+}
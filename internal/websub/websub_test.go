@@ -0,0 +1,39 @@
+package websub
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"testing"
+)
+
+func sign(h func() hash.Hash, secret string, body []byte) string {
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte("<rss></rss>")
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"valid sha256", "sha256=" + sign(sha256.New, "secret", body), true},
+		{"valid sha1", "sha1=" + sign(sha1.New, "secret", body), true},
+		{"wrong secret", "sha256=" + sign(sha256.New, "other", body), false},
+		{"unknown algorithm", "md5=" + sign(sha256.New, "secret", body), false},
+		{"algorithm mismatch", "sha1=" + sign(sha256.New, "secret", body), false},
+		{"malformed hex", "sha256=zz" + sign(sha256.New, "secret", body)[2:], false},
+		{"missing method", sign(sha256.New, "secret", body), false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		if got := VerifySignature("secret", tt.header, body); got != tt.want {
+			t.Errorf("%s: VerifySignature(%q) = %v, want %v", tt.name, tt.header, got, tt.want)
+		}
+	}
+}
@@ -8,16 +8,22 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
-	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/1-ashraful-islam/blog-aggregator/internal/auth"
 	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/digest"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/events"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/jobs"
 	"github.com/1-ashraful-islam/blog-aggregator/internal/scrapper"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/webhook"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/websub"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 
@@ -31,8 +37,31 @@ import (
 )
 
 type apiConfig struct {
-	DB     *database.Queries
+	DB *database.Queries
+	// Conn is the connection pool behind DB, used to run queries in a transaction
+	Conn   *sql.DB
 	Logger *log.Logger
+	Events events.Bus
+	// Webhooks sends post.created events to user webhooks
+	Webhooks *webhook.Dispatcher
+	// Jobs runs background work requested through the API, like feed refreshes
+	Jobs *jobs.Queue
+	// WebSub subscribes feeds that advertise a hub, nil when PUBLIC_URL is not set
+	// since hubs could not reach the callback
+	WebSub *websub.Subscriber
+	// APIKeyMaxLifetime caps how long an API key is valid, 0 means keys do not expire
+	// unless created with an expiry date
+	APIKeyMaxLifetime time.Duration
+	// OIDC logs users in through an OpenID Connect provider, nil when OIDC_ISSUER_URL is not set
+	OIDC *auth.OIDC
+	// OIDCLoginRedirect is where the browser goes after logging in through OIDC. The
+	// session is returned as JSON instead when it is empty.
+	OIDCLoginRedirect string
+	// SecureCookies marks the session cookie Secure, set when PUBLIC_URL is https
+	SecureCookies bool
+	// PublicURL is the externally reachable base URL of the server, used when
+	// building links handed out to other services. Derived from the request if empty.
+	PublicURL string
 }
 
 func (cfg *apiConfig) ScrapeFeeds(ctx context.Context, t time.Duration, n int32) {
@@ -56,10 +85,12 @@ func (cfg *apiConfig) ScrapeFeeds(ctx context.Context, t time.Duration, n int32)
 			go func(feed database.Feed) {
 				defer wg.Done()
 
-				err := scrapper.ScrapeFeed(ctx, cfg.DB, feed)
+				result, err := scrapper.ScrapeFeed(ctx, cfg.DB, cfg.Events, feed)
 				if err != nil {
 					cfg.Logger.Printf("Failed to scrape feed: %+v", err)
+					return
 				}
+				cfg.ensureWebSub(ctx, feed, result)
 			}(feed)
 		}
 		wg.Wait()
@@ -80,12 +111,36 @@ func (cfg *apiConfig) ScrapeFeeds(ctx context.Context, t time.Duration, n int32)
 
 }
 
+func (cfg *apiConfig) publishFeedCreated(ctx context.Context, feed database.Feed) {
+	if err := cfg.Events.Publish(ctx, events.Event{
+		Type:   events.FeedCreated,
+		FeedID: feed.ID,
+		At:     feed.CreatedAt,
+	}); err != nil {
+		cfg.Logger.Printf("Failed to publish %s for %v: %+v", events.FeedCreated, feed.ID, err)
+	}
+}
+
 func (cfg *apiConfig) ScrapeNewFeeds(ctx context.Context, feed database.Feed) {
 	//scrape a single feed when a new feed is added
-	if err := scrapper.ScrapeFeed(ctx, cfg.DB, feed); err != nil {
+	result, err := scrapper.ScrapeFeed(ctx, cfg.DB, cfg.Events, feed)
+	if err != nil {
 		cfg.Logger.Printf("Failed to scrape feed: %+v", err)
+		return
 	}
 	cfg.Logger.Printf("Scraped single new feed: %v", feed.Url)
+	cfg.ensureWebSub(ctx, feed, result)
+}
+
+// ensureWebSub subscribes to the feed's hub, if it advertises one, so new posts
+// are pushed instead of waiting for the next scrape.
+func (cfg *apiConfig) ensureWebSub(ctx context.Context, feed database.Feed, result scrapper.Result) {
+	if cfg.WebSub == nil || result.Hub == "" {
+		return
+	}
+	if err := cfg.WebSub.Ensure(ctx, feed, result.Hub, result.Self); err != nil {
+		cfg.Logger.Printf("Failed to subscribe to WebSub hub %v for feed %v: %+v", result.Hub, feed.Url, err)
+	}
 }
 
 type authedHandler func(w http.ResponseWriter, r *http.Request, u database.User)
@@ -95,6 +150,14 @@ func (cfg *apiConfig) middlewareAuth(handler authedHandler) http.HandlerFunc {
 		// get api key from header
 		authHeader := r.Header.Get("Authorization")
 
+		// the webui logs in with a password and sends a session cookie instead
+		if authHeader == "" {
+			if cookie, err := r.Cookie(sessionCookieName); err == nil {
+				cfg.authenticateSession(w, r, cookie.Value, handler)
+				return
+			}
+		}
+
 		authHeaderParts := strings.Split(authHeader, " ")
 		if len(authHeaderParts) != 2 || strings.ToLower(authHeaderParts[0]) != "bearer" {
 			respondWithError(w, http.StatusUnauthorized, "Invalid authorization header. Expected format: 'Bearer <API key>'")
@@ -107,14 +170,59 @@ func (cfg *apiConfig) middlewareAuth(handler authedHandler) http.HandlerFunc {
 			return
 		}
 
-		user, err := cfg.DB.GetUser(r.Context(), apiKey)
+		key, err := cfg.DB.GetApiKeyByHash(r.Context(), auth.HashAPIKey(apiKey))
 		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, "Invalid API key: "+apiKey)
+			respondWithError(w, http.StatusUnauthorized, "Invalid API key")
 			return
 		}
 
+		if cfg.apiKeyExpired(key, time.Now()) {
+			respondWithError(w, http.StatusUnauthorized, "API key has expired")
+			return
+		}
+
+		user, err := cfg.DB.GetUserByID(r.Context(), key.UserID)
+		if err != nil {
+			cfg.Logger.Printf("Failed to get user %v for API key %v: %+v", key.UserID, key.ID, err)
+			respondWithError(w, http.StatusUnauthorized, "Invalid API key")
+			return
+		}
+		if user.DisabledAt.Valid {
+			respondWithError(w, http.StatusForbidden, "Account is disabled")
+			return
+		}
+
+		if err := cfg.DB.TouchApiKey(r.Context(), database.TouchApiKeyParams{
+			Now: sql.NullTime{Time: time.Now(), Valid: true},
+			ID:  key.ID,
+		}); err != nil {
+			cfg.Logger.Printf("Failed to record use of API key %v: %+v", key.ID, err)
+		}
+
 		//call the handler with the authenticated user
-		handler(w, r, user)
+		handler(w, r.WithContext(auth.WithAPIKey(r.Context(), key)), user)
+	}
+}
+
+// middlewareScope only calls handler when the API key of the request was granted
+// scope. It goes inside middlewareAuth, which stores the key in the request context.
+// Sessions are not limited by scopes.
+func (cfg *apiConfig) middlewareScope(scope string, handler authedHandler) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, u database.User) {
+		if _, ok := auth.SessionFromContext(r.Context()); ok {
+			handler(w, r, u)
+			return
+		}
+
+		key, ok := auth.APIKeyFromContext(r.Context())
+		if !ok {
+			cfg.Logger.Printf("No API key in the context of %s %s", r.Method, r.URL.Path)
+		}
+		if !ok || !auth.HasScope(key.Scopes, scope) {
+			respondWithError(w, http.StatusForbidden, fmt.Sprintf("API key is missing the %s scope", scope))
+			return
+		}
+		handler(w, r, u)
 	}
 }
 
@@ -140,12 +248,6 @@ func (cfg *apiConfig) handlerUsersPost() http.HandlerFunc {
 			return
 		}
 
-		//check if user already exists
-		if _, err := cfg.DB.GetUserByName(r.Context(), u.Name); err == nil {
-			respondWithError(w, http.StatusBadRequest, "User already exists")
-			return
-		}
-
 		// Create a new user
 		createdUser, err := cfg.DB.CreateUser(r.Context(), database.CreateUserParams{
 			ID:        uuid.New(),
@@ -154,17 +256,46 @@ func (cfg *apiConfig) handlerUsersPost() http.HandlerFunc {
 			Name:      u.Name,
 		})
 
+		if isUniqueViolation(err) {
+			respondWithError(w, http.StatusConflict, "User already exists")
+			return
+		}
 		if err != nil {
 			cfg.Logger.Printf("Failed to create user: %+v", err)
 			respondWithError(w, http.StatusInternalServerError, "Failed to create user")
 			return
 		}
 
-		respondWithJSON(w, http.StatusCreated, createdUser)
+		// the first key is only shown in this response. It is the only key created
+		// with admin by default, so the new user can create narrower keys with it
+		_, key, err := cfg.createApiKey(r.Context(), createdUser.ID, "default", []string{auth.ScopeAdmin}, cfg.defaultApiKeyExpiry())
+		if err != nil {
+			cfg.Logger.Printf("Failed to create API key for user %v: %+v", createdUser.ID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to create user")
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, struct {
+			database.User
+			ApiKey string `json:"api_key"`
+		}{
+			User:   createdUser,
+			ApiKey: key,
+		})
 
 	}
 }
 
+// Feed statuses. A feed added through the API is pending until its first fetch
+// validates it in the background.
+const (
+	feedStatusPending = "pending"
+	feedStatusActive  = "active"
+	feedStatusFailed  = "failed"
+)
+
+// handlerFeedsPost adds a pending feed, follows it for the user and queues a job
+// that validates and scrapes it. Clients follow the job or the feed's status.
 func (cfg *apiConfig) handlerFeedsPost(w http.ResponseWriter, r *http.Request, u database.User) {
 	var f struct {
 		URL string `json:"url"`
@@ -181,65 +312,156 @@ func (cfg *apiConfig) handlerFeedsPost(w http.ResponseWriter, r *http.Request, u
 		respondWithError(w, http.StatusBadRequest, "url is required")
 		return
 	}
+	if parsedURL, err := url.ParseRequestURI(f.URL); err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		respondWithError(w, http.StatusBadRequest, "url must be an absolute http or https URL")
+		return
+	}
 
 	// check if feed already exists
-	if _, err := cfg.DB.GetFeedByURL(r.Context(), f.URL); err == nil {
-		respondWithError(w, http.StatusBadRequest, "Feed already exists")
+	feed, err := cfg.DB.GetFeedByURL(r.Context(), f.URL)
+	if err == nil && feed.Status != feedStatusFailed {
+		respondWithError(w, http.StatusConflict, "Feed already exists")
 		return
 	}
 
-	// validate feed and also get the title and description
-	feedInfo, err := scrapper.FetchFeedInfo(r.Context(), f.URL)
-	if err != nil {
-		cfg.Logger.Printf("Failed to fetch feed data: %+v", err)
-		respondWithError(w, http.StatusBadRequest, "Failed to fetch feed data, check if the URL is valid and try again.")
+	if err == nil {
+		// adding a failed feed again tries it once more
+		feed, err = cfg.DB.SetFeedStatus(r.Context(), database.SetFeedStatusParams{
+			ID:        feed.ID,
+			Status:    feedStatusPending,
+			UpdatedAt: time.Now(),
+		})
+	} else {
+		feed, err = cfg.DB.CreateFeed(r.Context(), database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    u.ID,
+			Url:       f.URL,
+			// replaced by the channel title once the feed is fetched
+			Title:  f.URL,
+			Status: feedStatusPending,
+		})
+	}
+	if isUniqueViolation(err) {
+		// added concurrently by someone else
+		respondWithError(w, http.StatusConflict, "Feed already exists")
 		return
 	}
-
-	feed, err := cfg.DB.CreateFeed(r.Context(), database.CreateFeedParams{
-		ID:          uuid.New(),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-		UserID:      u.ID,
-		Url:         f.URL,
-		Title:       feedInfo.Title,
-		Description: feedInfo.Description,
-	})
-
 	if err != nil {
-
 		cfg.Logger.Printf("Failed to create feed: %+v", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to create feed")
 		return
 	}
 
 	// create feed_follow for the user
-	feed_follow, err := cfg.DB.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		FeedID:    feed.ID,
-		UserID:    u.ID,
-	})
-
+	feed_follow, err := cfg.DB.GetFeedFollows(r.Context(), database.GetFeedFollowsParams{FeedID: feed.ID, UserID: u.ID})
+	if err != nil {
+		feed_follow, err = cfg.DB.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			FeedID:    feed.ID,
+			UserID:    u.ID,
+		})
+		if isUniqueViolation(err) {
+			feed_follow, err = cfg.DB.GetFeedFollows(r.Context(), database.GetFeedFollowsParams{FeedID: feed.ID, UserID: u.ID})
+		}
+	}
 	if err != nil {
 		cfg.Logger.Printf("Failed to create feed follow: %+v", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to create feed follow")
 		return
 	}
+
+	job, err := cfg.Jobs.Enqueue(r.Context(), jobs.KindCreateFeed, u.ID, feed.ID)
+	if err != nil {
+		cfg.Logger.Printf("Failed to queue fetch of new feed %v: %+v", feed.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create feed")
+		return
+	}
+
 	var result = struct {
 		Feed       database.Feed       `json:"feed"`
 		FeedFollow database.FeedFollow `json:"feed_follow"`
+		Job        database.Job        `json:"job"`
 	}{
 		Feed:       feed,
 		FeedFollow: feed_follow,
+		Job:        job,
 	}
 
-	respondWithJSON(w, http.StatusCreated, result)
-	//scrape the feed
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	cfg.ScrapeNewFeeds(ctx, feed)
+	w.Header().Set("Location", "/v1/feeds/"+feed.ID.String())
+	respondWithJSON(w, http.StatusAccepted, result)
+}
+
+// runCreateFeedJob is the jobs.Handler for jobs.KindCreateFeed. It fetches the
+// feed once to validate it, fill in its title and store its first posts.
+func (cfg *apiConfig) runCreateFeedJob(ctx context.Context, job database.Job) (int, error) {
+	feed, err := cfg.DB.GetFeedByID(ctx, job.FeedID)
+	if err != nil {
+		return 0, errors.Wrap(err, "getting feed")
+	}
+
+	fail := func(cause error) (int, error) {
+		if _, err := cfg.DB.SetFeedStatus(ctx, database.SetFeedStatusParams{
+			ID:          feed.ID,
+			Status:      feedStatusFailed,
+			StatusError: sql.NullString{String: "Failed to fetch feed data, check if the URL is valid and try again.", Valid: true},
+			UpdatedAt:   time.Now(),
+		}); err != nil {
+			cfg.Logger.Printf("Failed to mark feed %v as failed: %+v", feed.ID, err)
+		}
+		return 0, cause
+	}
+
+	body, err := scrapper.FetchFeed(ctx, feed.Url)
+	if err != nil {
+		return fail(err)
+	}
+	feedInfo, err := scrapper.ParseFeedInfo(feed.Url, body)
+	if err != nil {
+		return fail(err)
+	}
+
+	title := feedInfo.Title
+	if title == "" {
+		title = feed.Url
+	}
+	feed, err = cfg.DB.ActivateFeed(ctx, database.ActivateFeedParams{
+		ID:          feed.ID,
+		Title:       title,
+		Description: feedInfo.Description,
+		Link:        feedInfo.Link,
+		UpdatedAt:   time.Now(),
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "activating feed")
+	}
+	cfg.publishFeedCreated(ctx, feed)
+
+	result, err := scrapper.IngestFeed(ctx, cfg.DB, cfg.Events, feed, body)
+	if err != nil {
+		return result.NewPosts, err
+	}
+	cfg.ensureWebSub(ctx, feed, result)
+	return result.NewPosts, nil
+}
+
+func (cfg *apiConfig) handlerFeedGet(w http.ResponseWriter, r *http.Request) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "feed_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid feed_id")
+		return
+	}
+
+	feed, err := cfg.DB.GetFeedByID(r.Context(), feedID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Feed does not exist")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, feed)
 }
 
 func (cfg *apiConfig) handlerFeedsGet() http.HandlerFunc {
@@ -289,12 +511,6 @@ func (cfg *apiConfig) handlerFeedFollowsPost(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// check if feed_follow already exists
-	if _, err := cfg.DB.GetFeedFollows(r.Context(), database.GetFeedFollowsParams{FeedID: ff.FeedID, UserID: u.ID}); err == nil {
-		respondWithError(w, http.StatusBadRequest, "Feed follow already exists")
-		return
-	}
-
 	feedFollow, err := cfg.DB.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
@@ -303,6 +519,10 @@ func (cfg *apiConfig) handlerFeedFollowsPost(w http.ResponseWriter, r *http.Requ
 		UserID:    u.ID,
 	})
 
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Feed follow already exists")
+		return
+	}
 	if err != nil {
 		cfg.Logger.Printf("Failed to create feed follow: %+v", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to create feed follow")
@@ -346,37 +566,215 @@ func (cfg *apiConfig) handlerFeedFollowsDelete(w http.ResponseWriter, r *http.Re
 
 }
 
+// handlerFeedFollowsPatch updates the caller's settings on one of their feed follows.
+// A null folder_id removes the feed from its folder and a null or empty display_name
+// shows the feed title again. notify turns digests and webhooks for the feed on or
+// off, hidden keeps its posts out of the timeline.
+func (cfg *apiConfig) handlerFeedFollowsPatch(w http.ResponseWriter, r *http.Request, u database.User) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "feed_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid feed_id")
+		return
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		cfg.Logger.Printf("Failed to decode request body: %+v", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload. Please provide a valid JSON object")
+		return
+	}
+	defer r.Body.Close()
+
+	feedFollow, err := cfg.DB.GetFeedFollows(r.Context(), database.GetFeedFollowsParams{FeedID: feedID, UserID: u.ID})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Feed follow does not exist")
+		return
+	}
+
+	// every field is checked before anything is written, and written in one update
+	params := database.UpdateFeedFollowParams{
+		FeedID:      feedID,
+		UserID:      u.ID,
+		FolderID:    feedFollow.FolderID,
+		DisplayName: feedFollow.DisplayName,
+		Notify:      feedFollow.Notify,
+		Hidden:      feedFollow.Hidden,
+	}
+
+	if raw, ok := body["folder_id"]; ok {
+		if err := json.Unmarshal(raw, &params.FolderID); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid folder_id")
+			return
+		}
+
+		if params.FolderID.Valid {
+			if _, err := cfg.DB.GetFolderByID(r.Context(), database.GetFolderByIDParams{ID: params.FolderID.UUID, UserID: u.ID}); err != nil {
+				respondWithError(w, http.StatusBadRequest, "Folder does not exist")
+				return
+			}
+		}
+	}
+	if raw, ok := body["display_name"]; ok {
+		var displayName *string
+		if err := json.Unmarshal(raw, &displayName); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid display_name")
+			return
+		}
+		params.DisplayName = sql.NullString{}
+		if displayName != nil && strings.TrimSpace(*displayName) != "" {
+			params.DisplayName = sql.NullString{String: strings.TrimSpace(*displayName), Valid: true}
+		}
+	}
+	if raw, ok := body["notify"]; ok {
+		if err := json.Unmarshal(raw, &params.Notify); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid notify")
+			return
+		}
+	}
+	if raw, ok := body["hidden"]; ok {
+		if err := json.Unmarshal(raw, &params.Hidden); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid hidden")
+			return
+		}
+	}
+
+	params.UpdatedAt = time.Now()
+	feedFollow, err = cfg.DB.UpdateFeedFollow(r.Context(), params)
+	if err != nil {
+		cfg.Logger.Printf("Failed to update feed follow for feed_id %v: %+v", feedID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update feed follow")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, feedFollow)
+}
+
+// handlerFeedFollowsReadPost marks every post of a followed feed as read.
+func (cfg *apiConfig) handlerFeedFollowsReadPost(w http.ResponseWriter, r *http.Request, u database.User) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "feed_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid feed_id")
+		return
+	}
+
+	feedFollow, err := cfg.DB.MarkFeedFollowRead(r.Context(), database.MarkFeedFollowReadParams{
+		FeedID:     feedID,
+		UserID:     u.ID,
+		LastReadAt: time.Now(),
+	})
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Feed follow does not exist")
+		return
+	}
+	if err != nil {
+		cfg.Logger.Printf("Failed to mark feed %v as read: %+v", feedID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to mark feed as read")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, feedFollow)
+}
+
 func (cfg *apiConfig) handlerPostsGet(w http.ResponseWriter, r *http.Request, u database.User) {
 	queries := r.URL.Query()
-	offsetQ := queries.Get("offset")
-	limitQ := queries.Get("limit")
+	page, err := parsePageRequest(queries)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	if offsetQ == "" {
-		offsetQ = "0" // default to 0
+	filters, err := parsePostFilters(queries)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-	if limitQ == "" {
-		limitQ = "10" // default to 10
+
+	scope := queries.Get("scope")
+	if scope != "" && scope != "followed" && scope != "owned" {
+		respondWithError(w, http.StatusBadRequest, "Invalid scope. Expected 'followed' or 'owned'")
+		return
 	}
-	//convert to int
-	offset64, err1 := strconv.ParseInt(offsetQ, 10, 32)
-	limit64, err2 := strconv.ParseInt(limitQ, 10, 32)
-	if err1 != nil || err2 != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid offset or limit")
+
+	if filters.active() && (page.UseOffset || scope == "owned") {
+		respondWithError(w, http.StatusBadRequest, "Filters are only supported on the followed timeline with cursor pagination")
 		return
 	}
 
-	posts, err := cfg.DB.GetPostsByUser(r.Context(), database.GetPostsByUserParams{
-		UserID: u.ID,
-		Offset: int32(offset64),
-		Limit:  int32(limit64),
-	})
-	if err != nil || len(posts) == 0 {
+	if page.Cursor != nil && page.Cursor.Sort != filters.Sort {
+		respondWithError(w, http.StatusBadRequest, "Cursor does not match the requested sort")
+		return
+	}
+
+	if page.UseOffset {
+		// deprecated: offset pagination returns a bare array
+		var posts []database.Post
+		if scope == "owned" {
+			// creator view: only posts from feeds the user added
+			posts, err = cfg.DB.GetPostsByFeedOwner(r.Context(), database.GetPostsByFeedOwnerParams{
+				UserID: u.ID,
+				Offset: page.Offset,
+				Limit:  page.Limit,
+			})
+		} else {
+			// posts from every feed the user follows, including feeds created by others
+			posts, err = cfg.DB.GetPostsByUser(r.Context(), database.GetPostsByUserParams{
+				UserID: u.ID,
+				Offset: page.Offset,
+				Limit:  page.Limit,
+			})
+		}
+		if err != nil || len(posts) == 0 {
+			cfg.Logger.Printf("Failed to get posts for user %v: %+v", u.ID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get posts")
+			return
+		}
+
+		markOffsetDeprecated(w)
+		respondWithJSON(w, http.StatusOK, posts)
+		return
+	}
+
+	var posts []database.Post
+	switch {
+	case scope == "owned":
+		posts, err = cfg.DB.GetPostsByFeedOwnerPage(r.Context(), database.GetPostsByFeedOwnerPageParams{
+			UserID:     u.ID,
+			BeforeDate: page.beforeDate(),
+			BeforeID:   page.beforeID(),
+			PageLimit:  page.queryLimit(),
+		})
+	case filters.Sort == sortIngested:
+		posts, err = cfg.DB.GetFilteredPostsByUserIngested(r.Context(), database.GetFilteredPostsByUserIngestedParams{
+			UserID:     u.ID,
+			FolderID:   filters.FolderID,
+			Since:      filters.Since,
+			Until:      filters.Until,
+			FeedIds:    filters.FeedIDs,
+			Query:      filters.Query,
+			BeforeDate: page.beforeDate(),
+			BeforeID:   page.beforeID(),
+			PageLimit:  page.queryLimit(),
+		})
+	default:
+		posts, err = cfg.DB.GetFilteredPostsByUser(r.Context(), database.GetFilteredPostsByUserParams{
+			UserID:     u.ID,
+			FolderID:   filters.FolderID,
+			Since:      filters.Since,
+			Until:      filters.Until,
+			FeedIds:    filters.FeedIDs,
+			Query:      filters.Query,
+			BeforeDate: page.beforeDate(),
+			BeforeID:   page.beforeID(),
+			PageLimit:  page.queryLimit(),
+		})
+	}
+	if err != nil {
 		cfg.Logger.Printf("Failed to get posts for user %v: %+v", u.ID, err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to get posts")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, posts)
+	respondWithJSON(w, http.StatusOK, newPostsPage(posts, page, filters.Sort))
 }
 
 func (cfg *apiConfig) handlerPostsFeedIdGet(w http.ResponseWriter, r *http.Request) {
@@ -392,36 +790,47 @@ func (cfg *apiConfig) handlerPostsFeedIdGet(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	queries := r.URL.Query()
-	offsetQ := queries.Get("offset")
-	limitQ := queries.Get("limit")
-
-	if offsetQ == "" {
-		offsetQ = "0" // default to 0
+	page, err := parsePageRequest(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-	if limitQ == "" {
-		limitQ = "10" // default to 10
+	if page.Cursor != nil && page.Cursor.Sort != sortPublished {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
 	}
-	//convert to int
-	offset64, err1 := strconv.ParseInt(offsetQ, 10, 32)
-	limit64, err2 := strconv.ParseInt(limitQ, 10, 32)
-	if err1 != nil || err2 != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid offset or limit")
+
+	if page.UseOffset {
+		// deprecated: offset pagination returns a bare array
+		posts, err := cfg.DB.GetPostsByFeedID(r.Context(), database.GetPostsByFeedIDParams{
+			FeedID: fID,
+			Offset: page.Offset,
+			Limit:  page.Limit,
+		})
+		if err != nil || len(posts) == 0 {
+			cfg.Logger.Printf("Failed to get posts for feed id %v: %+v", fID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get posts")
+			return
+		}
+
+		markOffsetDeprecated(w)
+		respondWithJSON(w, http.StatusOK, posts)
 		return
 	}
 
-	posts, err := cfg.DB.GetPostsByFeedID(r.Context(), database.GetPostsByFeedIDParams{
-		FeedID: fID,
-		Offset: int32(offset64),
-		Limit:  int32(limit64),
+	posts, err := cfg.DB.GetPostsByFeedIDPage(r.Context(), database.GetPostsByFeedIDPageParams{
+		FeedID:     fID,
+		BeforeDate: page.beforeDate(),
+		BeforeID:   page.beforeID(),
+		PageLimit:  page.queryLimit(),
 	})
-	if err != nil || len(posts) == 0 {
+	if err != nil {
 		cfg.Logger.Printf("Failed to get posts for feed id %v: %+v", fID, err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to get posts")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, posts)
+	respondWithJSON(w, http.StatusOK, newPostsPage(posts, page, sortPublished))
 }
 
 func main() {
@@ -458,10 +867,84 @@ func main() {
 
 	dbQueries := database.New(db)
 
+	// events reach every server instance through Postgres unless EVENT_BUS=memory
+	var eventBus events.Bus
+	if os.Getenv("EVENT_BUS") == "memory" {
+		eventBus = events.NewMemoryBus()
+	} else {
+		eventBus, err = events.NewPostgresBus(db, os.Getenv("DATABASE_URL"), logger)
+		if err != nil {
+			logger.Fatalf(errors.Wrap(err, "could not start the event bus").Error())
+		}
+	}
+
 	// Create a new instance of the API config
 	apiConfig := &apiConfig{
-		DB:     dbQueries,
-		Logger: logger,
+		DB:        dbQueries,
+		Conn:      db,
+		Logger:    logger,
+		Events:    eventBus,
+		Webhooks:  webhook.NewDispatcher(dbQueries, logger),
+		Jobs:      jobs.NewQueue(dbQueries, 4, logger),
+		PublicURL: strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/"),
+	}
+	apiConfig.SecureCookies = strings.HasPrefix(apiConfig.PublicURL, "https://")
+	// the first administrators are listed by id in ADMIN_USER_IDS, they can promote others.
+	// Names are chosen by the users themselves, so they can't be trusted for this.
+	if adminUserIDs := os.Getenv("ADMIN_USER_IDS"); adminUserIDs != "" {
+		var ids []uuid.UUID
+		for _, id := range strings.Split(adminUserIDs, ",") {
+			if id = strings.TrimSpace(id); id == "" {
+				continue
+			}
+			userID, err := uuid.Parse(id)
+			if err != nil {
+				logger.Fatalf("Invalid user id %q in ADMIN_USER_IDS: %v", id, err)
+			}
+			ids = append(ids, userID)
+		}
+		promoted, err := dbQueries.PromoteUsersToAdmin(context.Background(), database.PromoteUsersToAdminParams{
+			UpdatedAt: time.Now(),
+			Ids:       ids,
+		})
+		if err != nil {
+			logger.Fatalf(errors.Wrap(err, "could not promote ADMIN_USER_IDS").Error())
+		}
+		if promoted > 0 {
+			logger.Printf("Promoted %d users from ADMIN_USER_IDS to administrators", promoted)
+		}
+	}
+	if issuerURL := os.Getenv("OIDC_ISSUER_URL"); issuerURL != "" {
+		redirectURL := os.Getenv("OIDC_REDIRECT_URL")
+		if redirectURL == "" {
+			if apiConfig.PublicURL == "" {
+				logger.Fatalf("OIDC_ISSUER_URL is set but neither OIDC_REDIRECT_URL nor PUBLIC_URL is")
+			}
+			redirectURL = apiConfig.PublicURL + "/v1/auth/oidc/callback"
+		}
+		apiConfig.OIDC, err = auth.NewOIDC(context.Background(), auth.OIDCConfig{
+			IssuerURL:    issuerURL,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  redirectURL,
+		})
+		if err != nil {
+			logger.Fatalf(errors.Wrap(err, "could not set up single sign-on").Error())
+		}
+		apiConfig.OIDCLoginRedirect = os.Getenv("OIDC_LOGIN_REDIRECT")
+	}
+	if maxLifetime := os.Getenv("API_KEY_MAX_LIFETIME"); maxLifetime != "" {
+		apiConfig.APIKeyMaxLifetime, err = time.ParseDuration(maxLifetime)
+		if err != nil || apiConfig.APIKeyMaxLifetime < 0 {
+			logger.Fatalf("Invalid API_KEY_MAX_LIFETIME %q: %v", maxLifetime, err)
+		}
+	}
+	apiConfig.Jobs.Handle(jobs.KindRefresh, apiConfig.runRefreshJob)
+	apiConfig.Jobs.Handle(jobs.KindCreateFeed, apiConfig.runCreateFeedJob)
+	if apiConfig.PublicURL != "" {
+		apiConfig.WebSub = websub.NewSubscriber(dbQueries, apiConfig.PublicURL, logger)
+	} else {
+		logger.Printf("PUBLIC_URL is not set, WebSub push updates and output feeds are disabled")
 	}
 
 	port := os.Getenv("PORT")
@@ -473,7 +956,7 @@ func main() {
 	// middlewares
 	r.Use(middleware.Logger)
 	// cors
-	r.Use(middlewareCors())
+	r.Use(middlewareCors(os.Getenv("CORS_ALLOWED_ORIGINS"), logger))
 	// rate limiter : rate limit 40 requests per second per IP
 	r.Use(httprate.LimitByIP(40, 1*time.Second))
 
@@ -491,6 +974,13 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// closing the bus ends long-lived event streams so Shutdown does not wait on them
+	srv.RegisterOnShutdown(func() {
+		if err := apiConfig.Events.Close(); err != nil {
+			logger.Printf("Failed to close event bus: %v", err)
+		}
+	})
+
 	// test the scrapper
 	scraperIntervalStr := os.Getenv("SCRAPER_INTERVAL")
 	scraperInterval, err := time.ParseDuration(scraperIntervalStr)
@@ -499,6 +989,31 @@ func main() {
 		scraperInterval = 10 * time.Minute
 	}
 	go apiConfig.ScrapeFeeds(ctx, scraperInterval, 10)
+	go apiConfig.Webhooks.Run(ctx, apiConfig.Events)
+	go apiConfig.Jobs.Run(ctx)
+	go apiConfig.PruneSessions(ctx, time.Hour)
+	if apiConfig.WebSub != nil {
+		go apiConfig.WebSub.Run(ctx)
+	}
+
+	// email digests are only sent when an SMTP server is configured
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		baseURL := apiConfig.PublicURL
+		if baseURL == "" {
+			logger.Printf("PUBLIC_URL is not set, digest unsubscribe links point to localhost")
+			baseURL = "http://localhost:" + port
+		}
+		digestSender := digest.NewSender(dbQueries, digest.SMTPConfig{
+			Host:     smtpHost,
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		}, baseURL, logger)
+		go digestSender.Run(ctx)
+	} else {
+		logger.Printf("SMTP_HOST is not set, email digests are disabled")
+	}
 
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -520,19 +1035,28 @@ func main() {
 
 }
 
-func middlewareCors() func(next http.Handler) http.Handler {
+// middlewareCors lets browsers call the API from other origins. Session cookies are
+// only sent along from allowedOrigins, a comma separated list. Without it any origin
+// may call the API with an API key.
+func middlewareCors(allowedOrigins string, logger *log.Logger) func(next http.Handler) http.Handler {
 	corsOptions := cors.Options{
-		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
-		AllowedOrigins: []string{"https://*", "http://*"},
-		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: false,
-		MaxAge:           300, // Maximum value not ignored by any of major browsers
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Last-Event-ID"},
+		ExposedHeaders: []string{"Link", "Deprecation", "Location", "Retry-After"},
+		MaxAge:         300, // Maximum value not ignored by any of major browsers
 	}
 
-	log.Println("CORS permissions are very permissive. Tighten before deploying to production")
+	for _, origin := range strings.Split(allowedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			corsOptions.AllowedOrigins = append(corsOptions.AllowedOrigins, origin)
+		}
+	}
+	if len(corsOptions.AllowedOrigins) > 0 {
+		corsOptions.AllowCredentials = true
+	} else {
+		corsOptions.AllowedOrigins = []string{"https://*", "http://*"}
+		logger.Println("CORS_ALLOWED_ORIGINS is not set, any origin may call the API but session cookies are not accepted cross-origin")
+	}
 
 	return cors.Handler(corsOptions)
 }
@@ -553,16 +1077,77 @@ func v1Router(apiConfig *apiConfig) http.Handler {
 
 	r.Post("/users", apiConfig.handlerUsersPost())
 	r.Get("/users", apiConfig.middlewareAuth(apiConfig.handlerUsersGet))
-
-	r.Post("/feeds", apiConfig.middlewareAuth(apiConfig.handlerFeedsPost))
+	r.Patch("/users", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerUsersPatch)))
+	r.Delete("/users", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerUsersDelete)))
+
+	// password logins are limited harder than the rest of the API to slow down guessing
+	r.With(httprate.LimitByIP(10, time.Minute)).Post("/auth/register", apiConfig.handlerAuthRegister)
+	r.With(httprate.LimitByIP(10, time.Minute)).Post("/auth/login", apiConfig.handlerAuthLogin)
+	r.Get("/auth/oidc/login", apiConfig.handlerOidcLogin)
+	r.Get("/auth/oidc/callback", apiConfig.handlerOidcCallback)
+	r.Get("/auth/session", apiConfig.middlewareAuth(apiConfig.handlerAuthSession))
+	r.Post("/auth/logout", apiConfig.middlewareAuth(apiConfig.handlerAuthLogout))
+
+	r.Get("/admin/users", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.middlewareAdmin(apiConfig.handlerAdminUsersGet))))
+	r.Patch("/admin/users/{user_id}", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.middlewareAdmin(apiConfig.handlerAdminUsersPatch))))
+	r.Delete("/admin/users/{user_id}", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.middlewareAdmin(apiConfig.handlerAdminUsersDelete))))
+	r.Put("/admin/feeds/{feed_id}/owner", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.middlewareAdmin(apiConfig.handlerAdminFeedOwnerPut))))
+
+	r.Post("/api_keys", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerApiKeysPost)))
+	r.Get("/api_keys", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerApiKeysGet)))
+	r.Delete("/api_keys/{api_key_id}", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerApiKeysDelete)))
+	r.Post("/api_keys/{api_key_id}/rotate", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerApiKeysRotate)))
+
+	r.Post("/feeds", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFeedsWrite, apiConfig.handlerFeedsPost)))
 	r.Get("/feeds", apiConfig.handlerFeedsGet())
+	r.Get("/feeds/{feed_id}", apiConfig.handlerFeedGet)
+	r.Patch("/feeds/{feed_id}", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFeedsWrite, apiConfig.handlerFeedsPatch)))
+	r.Delete("/feeds/{feed_id}", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFeedsWrite, apiConfig.handlerFeedsDelete)))
+	r.Post("/feeds/{feed_id}/refresh", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFeedsWrite, apiConfig.handlerFeedRefreshPost)))
+
+	r.Get("/jobs/{job_id}", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFeedsWrite, apiConfig.handlerJobGet)))
+
+	r.Post("/feed_follows", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFollowsWrite, apiConfig.handlerFeedFollowsPost)))
+	r.Get("/feed_follows", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFollowsRead, apiConfig.handlerFeedFollowsGet)))
+	r.Patch("/feed_follows/{feed_id}", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFollowsWrite, apiConfig.handlerFeedFollowsPatch)))
+	r.Delete("/feed_follows/{feed_id}", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFollowsWrite, apiConfig.handlerFeedFollowsDelete)))
+	r.Post("/feed_follows/{feed_id}/read", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFollowsWrite, apiConfig.handlerFeedFollowsReadPost)))
+	r.Get("/unread_counts", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopePostsRead, apiConfig.handlerUnreadCountsGet)))
+
+	r.Post("/folders", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFollowsWrite, apiConfig.handlerFoldersPost)))
+	r.Get("/folders", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFollowsRead, apiConfig.handlerFoldersGet)))
+	r.Patch("/folders/{folder_id}", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFollowsWrite, apiConfig.handlerFoldersPatch)))
+	r.Delete("/folders/{folder_id}", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFollowsWrite, apiConfig.handlerFoldersDelete)))
+
+	r.Get("/posts", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopePostsRead, apiConfig.handlerPostsGet)))
+	r.Get("/posts/{feed_id}", apiConfig.handlerPostsFeedIdGet)
 
-	r.Post("/feed_follows", apiConfig.middlewareAuth(apiConfig.handlerFeedFollowsPost))
-	r.Get("/feed_follows", apiConfig.middlewareAuth(apiConfig.handlerFeedFollowsGet))
-	r.Delete("/feed_follows/{feed_id}", apiConfig.middlewareAuth(apiConfig.handlerFeedFollowsDelete))
+	r.Get("/search", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopePostsRead, apiConfig.handlerSearchGet)))
 
-	r.Get("/posts", apiConfig.middlewareAuth(apiConfig.handlerPostsGet))
-	r.Get("/posts/{feed_id}", apiConfig.handlerPostsFeedIdGet)
+	r.Get("/stream", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopePostsRead, apiConfig.handlerStreamGet)))
+
+	r.Post("/opml", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFeedsWrite, apiConfig.middlewareScope(auth.ScopeFollowsWrite, apiConfig.handlerOpmlPost))))
+	r.Get("/opml", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeFollowsRead, apiConfig.handlerOpmlGet)))
+
+	r.Post("/output_feeds", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerOutputFeedsPost)))
+	r.Get("/output_feeds", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerOutputFeedsGet)))
+	r.Delete("/output_feeds/{output_feed_id}", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerOutputFeedsDelete)))
+	r.Get("/output_feeds/{token}/rss", apiConfig.handlerOutputFeedRender("rss"))
+	r.Get("/output_feeds/{token}/atom", apiConfig.handlerOutputFeedRender("atom"))
+
+	r.Get("/digest", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerDigestGet)))
+	r.Put("/digest", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerDigestPut)))
+	r.Get("/digest/unsubscribe/{token}", apiConfig.handlerDigestUnsubscribe)
+	r.Post("/digest/unsubscribe/{token}", apiConfig.handlerDigestUnsubscribe)
+
+	r.Get("/websub/{feed_id}", apiConfig.handlerWebsubVerify)
+	r.Post("/websub/{feed_id}", apiConfig.handlerWebsubContent)
+
+	r.Post("/webhooks", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerWebhooksPost)))
+	r.Get("/webhooks", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerWebhooksGet)))
+	r.Delete("/webhooks/{webhook_id}", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerWebhooksDelete)))
+	r.Get("/webhooks/{webhook_id}/deliveries", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerWebhookDeliveriesGet)))
+	r.Post("/webhooks/{webhook_id}/test", apiConfig.middlewareAuth(apiConfig.middlewareScope(auth.ScopeAdmin, apiConfig.handlerWebhookTestPost)))
 
 	return r
 }
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/auth"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/events"
+	"github.com/1-ashraful-islam/blog-aggregator/internal/jobs"
+	"github.com/google/uuid"
+)
+
+// concurrentRequests is how many identical requests the race tests send at once.
+const concurrentRequests = 10
+
+// newTestConfig returns an apiConfig backed by a fresh schema in the Postgres
+// database at TEST_DATABASE_URL, with every migration in sql/schema applied.
+// The test is skipped when TEST_DATABASE_URL is not set.
+func newTestConfig(t *testing.T) *apiConfig {
+	t.Helper()
+
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	// every test gets its own schema so tests can run against a database that has data
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("creating schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Logf("dropping schema %v: %v", schema, err)
+		}
+	})
+
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		t.Fatalf("parsing TEST_DATABASE_URL: %v", err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatalf("opening test schema: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrate(t, db)
+
+	bus := events.NewMemoryBus()
+	t.Cleanup(func() { bus.Close() })
+
+	logger := log.New(io.Discard, "", 0)
+	return &apiConfig{
+		DB:     database.New(db),
+		Conn:   db,
+		Logger: logger,
+		Events: bus,
+		// jobs are queued but not run, tests run their handlers when they need them
+		Jobs: jobs.NewQueue(database.New(db), 1, logger),
+	}
+}
+
+// migrate runs the Up section of every goose migration in order.
+func migrate(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join("sql", "schema", "*.sql"))
+	if err != nil {
+		t.Fatalf("listing migrations: %v", err)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("reading %v: %v", file, err)
+		}
+		up := string(content)
+		if i := strings.Index(up, "-- +goose Down"); i >= 0 {
+			up = up[:i]
+		}
+		if _, err := db.Exec(up); err != nil {
+			t.Fatalf("applying %v: %v", file, err)
+		}
+	}
+}
+
+// raceRequests sends n copies of the request built by newRequest at the same
+// time and returns how often each status code came back.
+func raceRequests(t *testing.T, handler http.Handler, n int, newRequest func() *http.Request) map[int]int {
+	t.Helper()
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		codes = make(map[int]int)
+		start = make(chan struct{})
+	)
+	for i := 0; i < n; i++ {
+		req := newRequest()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			mu.Lock()
+			codes[rec.Code]++
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return codes
+}
+
+func createTestUser(t *testing.T, cfg *apiConfig, name string) database.User {
+	t.Helper()
+
+	user, err := cfg.DB.CreateUser(context.Background(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      name,
+	})
+	if err != nil {
+		t.Fatalf("creating user %v: %v", name, err)
+	}
+	return user
+}
+
+// createTestFeed adds an active feed owned and followed by owner.
+func createTestFeed(t *testing.T, cfg *apiConfig, owner database.User, feedURL string) database.Feed {
+	t.Helper()
+
+	feed, err := cfg.DB.CreateFeed(context.Background(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    owner.ID,
+		Url:       feedURL,
+		Title:     feedURL,
+		Status:    feedStatusActive,
+	})
+	if err != nil {
+		t.Fatalf("creating feed %v: %v", feedURL, err)
+	}
+	followTestFeed(t, cfg, owner, feed)
+	return feed
+}
+
+func followTestFeed(t *testing.T, cfg *apiConfig, user database.User, feed database.Feed) {
+	t.Helper()
+
+	if _, err := cfg.DB.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		FeedID:    feed.ID,
+		UserID:    user.ID,
+	}); err != nil {
+		t.Fatalf("following feed %v: %v", feed.ID, err)
+	}
+}
+
+func assertOneCreated(t *testing.T, codes map[int]int, n int) {
+	t.Helper()
+
+	if codes[http.StatusCreated] != 1 || codes[http.StatusConflict] != n-1 {
+		t.Fatalf("expected 1 x 201 and %d x 409, got %v", n-1, codes)
+	}
+}
+
+func TestUsersPostConcurrentSameName(t *testing.T) {
+	cfg := newTestConfig(t)
+	router := v1Router(cfg)
+
+	codes := raceRequests(t, router, concurrentRequests, func() *http.Request {
+		body, _ := json.Marshal(map[string]string{"name": "alice"})
+		return httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(body))
+	})
+	assertOneCreated(t, codes, concurrentRequests)
+}
+
+func TestFeedFollowsPostConcurrentSamePair(t *testing.T) {
+	cfg := newTestConfig(t)
+	router := v1Router(cfg)
+	ctx := context.Background()
+
+	owner := createTestUser(t, cfg, "owner")
+	follower := createTestUser(t, cfg, "follower")
+	_, key, err := cfg.createApiKey(ctx, follower.ID, "test", []string{auth.ScopeFollowsWrite}, sql.NullTime{})
+	if err != nil {
+		t.Fatalf("creating API key: %v", err)
+	}
+	feed := createTestFeed(t, cfg, owner, "https://example.com/feed.xml")
+
+	codes := raceRequests(t, router, concurrentRequests, func() *http.Request {
+		body, _ := json.Marshal(map[string]uuid.UUID{"feed_id": feed.ID})
+		req := httptest.NewRequest(http.MethodPost, "/feed_follows", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		return req
+	})
+	assertOneCreated(t, codes, concurrentRequests)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/1-ashraful-islam/blog-aggregator/internal/database"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+const (
+	sortPublished = "published"
+	sortIngested  = "ingested"
+)
+
+// postCursor points at the last post of a page. The next page starts strictly
+// after it in (sort key DESC, id DESC) order, where the sort key is the publish
+// date or the ingestion time depending on Sort.
+type postCursor struct {
+	Sort string
+	Key  time.Time
+	ID   uuid.UUID
+}
+
+func newPostCursor(sort string, post database.Post) postCursor {
+	key := post.PublishDate
+	if sort == sortIngested {
+		key = post.CreatedAt
+	}
+	return postCursor{Sort: sort, Key: key, ID: post.ID}
+}
+
+func (c postCursor) encode() string {
+	raw := c.Sort + "|" + c.Key.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodePostCursor(s string) (postCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return postCursor{}, errors.Wrap(err, "decoding cursor")
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || (parts[0] != sortPublished && parts[0] != sortIngested) {
+		return postCursor{}, errors.New("malformed cursor")
+	}
+	key, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return postCursor{}, errors.Wrap(err, "parsing cursor date")
+	}
+	postID, err := uuid.Parse(parts[2])
+	if err != nil {
+		return postCursor{}, errors.Wrap(err, "parsing cursor id")
+	}
+	return postCursor{Sort: parts[0], Key: key, ID: postID}, nil
+}
+
+// pageRequest holds the pagination query parameters shared by the post listings.
+type pageRequest struct {
+	Limit int32
+	// Offset is only set when the client asked for the deprecated offset pagination.
+	Offset    int32
+	UseOffset bool
+	Cursor    *postCursor
+}
+
+func parsePageRequest(queries url.Values) (pageRequest, error) {
+	page := pageRequest{Limit: defaultPageSize}
+
+	if limitQ := queries.Get("limit"); limitQ != "" {
+		limit64, err := strconv.ParseInt(limitQ, 10, 32)
+		if err != nil || limit64 <= 0 {
+			return page, errors.New("Invalid limit")
+		}
+		page.Limit = int32(min(limit64, maxPageSize))
+	}
+
+	cursorQ := queries.Get("cursor")
+	offsetQ := queries.Get("offset")
+	if cursorQ != "" && offsetQ != "" {
+		return page, errors.New("cursor and offset cannot be used together")
+	}
+
+	if cursorQ != "" {
+		cursor, err := decodePostCursor(cursorQ)
+		if err != nil {
+			return page, errors.New("Invalid cursor")
+		}
+		page.Cursor = &cursor
+	}
+
+	if offsetQ != "" {
+		offset64, err := strconv.ParseInt(offsetQ, 10, 32)
+		if err != nil || offset64 < 0 {
+			return page, errors.New("Invalid offset")
+		}
+		page.Offset = int32(offset64)
+		page.UseOffset = true
+	}
+
+	return page, nil
+}
+
+// beforeDate and beforeID return the keyset bounds for the page query.
+func (p pageRequest) beforeDate() sql.NullTime {
+	if p.Cursor == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: p.Cursor.Key, Valid: true}
+}
+
+func (p pageRequest) beforeID() uuid.NullUUID {
+	if p.Cursor == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: p.Cursor.ID, Valid: true}
+}
+
+// queryLimit asks for one extra row so we know whether another page exists.
+func (p pageRequest) queryLimit() int32 {
+	return p.Limit + 1
+}
+
+type postsPage struct {
+	Posts      []database.Post `json:"posts"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+func newPostsPage(posts []database.Post, page pageRequest, sort string) postsPage {
+	result := postsPage{Posts: posts}
+	if result.Posts == nil {
+		result.Posts = []database.Post{}
+	}
+	if len(posts) > int(page.Limit) {
+		result.Posts = posts[:page.Limit]
+		last := result.Posts[len(result.Posts)-1]
+		result.NextCursor = newPostCursor(sort, last).encode()
+	}
+	return result
+}
+
+// markOffsetDeprecated flags responses served with offset pagination.
+func markOffsetDeprecated(w http.ResponseWriter) {
+	w.Header().Set("Deprecation", "true")
+}
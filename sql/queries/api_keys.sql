@@ -0,0 +1,31 @@
+-- name: CreateApiKey :one
+INSERT INTO api_keys (id, created_at, updated_at, user_id, name, prefix, key_hash, expires_at, scopes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetApiKeyByHash :one
+SELECT * FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL;
+
+-- name: GetApiKeyByID :one
+SELECT * FROM api_keys WHERE id = $1 AND user_id = $2;
+
+-- name: GetApiKeysByUser :many
+SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created_at;
+
+-- name: TouchApiKey :exec
+-- Records a use of the key, at most once a minute to spare a write per request.
+UPDATE api_keys SET last_used_at = @now
+WHERE id = @id AND (last_used_at IS NULL OR last_used_at < @now - interval '1 minute');
+
+-- name: RevokeApiKey :execrows
+UPDATE api_keys SET revoked_at = $3, updated_at = $3
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: ReplaceApiKey :execrows
+-- Points a rotated key to its replacement and shortens its life to the grace window.
+-- A key can only be replaced once.
+UPDATE api_keys SET
+  replaced_by = @replaced_by,
+  expires_at = LEAST(COALESCE(expires_at, @grace_until), @grace_until),
+  updated_at = @updated_at
+WHERE id = @id AND user_id = @user_id AND revoked_at IS NULL AND replaced_by IS NULL;
@@ -0,0 +1,44 @@
+-- name: UpsertDigestSettings :one
+INSERT INTO digest_settings (
+  user_id, created_at, updated_at, email, frequency, timezone, send_hour, send_weekday, unsubscribe_token
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+) ON CONFLICT (user_id) DO UPDATE SET
+  updated_at = EXCLUDED.updated_at,
+  email = EXCLUDED.email,
+  frequency = EXCLUDED.frequency,
+  timezone = EXCLUDED.timezone,
+  send_hour = EXCLUDED.send_hour,
+  send_weekday = EXCLUDED.send_weekday
+RETURNING *;
+
+-- name: GetDigestSettings :one
+SELECT * FROM digest_settings WHERE user_id = $1;
+
+-- name: GetActiveDigestSettings :many
+SELECT digest_settings.* FROM digest_settings
+JOIN users ON users.id = digest_settings.user_id
+WHERE digest_settings.frequency <> 'off' AND users.disabled_at IS NULL;
+
+-- name: UnsubscribeDigest :execrows
+UPDATE digest_settings SET frequency = 'off', updated_at = $2
+WHERE unsubscribe_token = $1;
+
+-- name: ClaimDigest :execrows
+-- Moves last_sent_at forward only if no other server instance did it first.
+UPDATE digest_settings SET last_sent_at = @sent_at
+WHERE user_id = @user_id AND last_sent_at IS NOT DISTINCT FROM @previous_sent_at;
+
+-- name: GetPostsForDigest :many
+SELECT posts.id, posts.title, posts.url, posts.publish_date, COALESCE(feed_follows.display_name, feeds.title)::text AS feed_title
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND feed_follows.notify AND posts.created_at > $2
+ORDER BY posts.publish_date DESC
+LIMIT $3;
+
+-- name: CountPostsForDigest :one
+SELECT COUNT(*) FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND feed_follows.notify AND posts.created_at > $2;
@@ -1,8 +1,8 @@
 -- name: CreateFeed :one
 INSERT INTO feeds (
-  id, created_at, updated_at, user_id, url, title, description
+  id, created_at, updated_at, user_id, url, title, description, link, status
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
 ) RETURNING *;
 
 -- name: GetFeeds :many
@@ -15,7 +15,33 @@ SELECT * FROM feeds WHERE url = $1;
 SELECT * FROM feeds WHERE id = $1;
 
 -- name: GetNextFeedsToFetch :many
-SELECT * FROM feeds ORDER BY last_fetched_at ASC NULLS FIRST LIMIT $1;
+SELECT * FROM feeds WHERE status = 'active' ORDER BY last_fetched_at ASC NULLS FIRST LIMIT $1;
 
 -- name: MarkFeedAsFetched :one
 UPDATE feeds SET last_fetched_at = $2, updated_at = $3 WHERE id = $1 RETURNING *;
+
+-- name: ActivateFeed :one
+UPDATE feeds SET status = 'active', status_error = NULL, title = COALESCE(title_override, $2), description = $3, link = $4, updated_at = $5
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedStatus :one
+UPDATE feeds SET status = $2, status_error = $3, updated_at = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedOwner :one
+UPDATE feeds SET user_id = $2, updated_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateFeed :one
+UPDATE feeds SET url = $2, title = $3, title_override = $4, description = $5, link = $6, updated_at = $7
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteFeed :execrows
+DELETE FROM feeds WHERE id = $1;
+
+-- name: GetFeedsByOwner :many
+SELECT * FROM feeds WHERE user_id = $1 ORDER BY created_at;
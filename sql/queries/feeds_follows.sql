@@ -1,15 +1,54 @@
 -- name: CreateFeedFollow :one
 INSERT INTO feed_follows (
-  id, created_at, updated_at, feed_id, user_id
+  id, created_at, updated_at, feed_id, user_id, folder_id
   ) VALUES (
-  $1, $2, $3, $4, $5
+  $1, $2, $3, $4, $5, $6
   ) RETURNING *;
 
 -- name: GetFeedFollows :one
 SELECT * FROM feed_follows WHERE feed_id = $1 AND user_id = $2;
 
 -- name: GetFeedFollowsByUser :many
-SELECT * FROM feeds WHERE id IN (SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = $1);
+SELECT feeds.*, feed_follows.folder_id, feed_follows.display_name, feed_follows.notify, feed_follows.hidden
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.created_at, feeds.id;
 
 -- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows WHERE feed_id = $1 AND user_id = $2;
+
+-- name: GetFeedFollowsWithFolderByUser :many
+SELECT feeds.id, feeds.url, COALESCE(feed_follows.display_name, feeds.title)::text AS title, feeds.description, feeds.link, folders.name AS folder_name
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+LEFT JOIN folders ON folders.id = feed_follows.folder_id
+WHERE feed_follows.user_id = $1
+ORDER BY folders.name NULLS FIRST, title;
+
+-- name: GetOldestOtherFollower :one
+-- Returns who has followed the feed the longest, apart from user_id.
+SELECT user_id FROM feed_follows
+WHERE feed_id = $1 AND user_id <> $2
+ORDER BY created_at, id
+LIMIT 1;
+
+-- name: UpdateFeedFollow :one
+UPDATE feed_follows SET folder_id = $3, display_name = $4, notify = $5, hidden = $6, updated_at = $7
+WHERE feed_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: MarkFeedFollowRead :one
+UPDATE feed_follows SET last_read_at = $3, updated_at = $3
+WHERE feed_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: GetUnreadCountsByUser :many
+SELECT feed_follows.feed_id, COUNT(posts.id) AS unread
+FROM feed_follows
+LEFT JOIN posts ON posts.feed_id = feed_follows.feed_id AND posts.created_at > feed_follows.last_read_at
+WHERE feed_follows.user_id = @user_id
+  AND NOT feed_follows.hidden
+  AND (sqlc.narg('folder_id')::uuid IS NULL OR feed_follows.folder_id = sqlc.narg('folder_id'))
+GROUP BY feed_follows.feed_id, feed_follows.created_at
+ORDER BY feed_follows.created_at, feed_follows.feed_id;
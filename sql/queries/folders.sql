@@ -0,0 +1,21 @@
+-- name: CreateFolder :one
+INSERT INTO folders (
+  id, created_at, updated_at, user_id, name
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetFoldersByUser :many
+SELECT * FROM folders WHERE user_id = $1 ORDER BY name;
+
+-- name: GetFolderByID :one
+SELECT * FROM folders WHERE id = $1 AND user_id = $2;
+
+-- name: GetFolderByName :one
+SELECT * FROM folders WHERE user_id = $1 AND name = $2;
+
+-- name: UpdateFolder :one
+UPDATE folders SET name = $3, updated_at = $4 WHERE id = $1 AND user_id = $2 RETURNING *;
+
+-- name: DeleteFolder :exec
+DELETE FROM folders WHERE id = $1 AND user_id = $2;
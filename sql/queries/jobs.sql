@@ -0,0 +1,40 @@
+-- name: CreateJob :one
+INSERT INTO jobs (id, created_at, updated_at, user_id, feed_id, kind)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetJobByID :one
+SELECT * FROM jobs WHERE id = $1 AND user_id = $2;
+
+-- name: GetLatestJobForFeed :one
+SELECT * FROM jobs WHERE feed_id = $1 AND kind = $2
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: CountJobsByUserSince :one
+SELECT COUNT(*) FROM jobs WHERE user_id = $1 AND kind = $2 AND created_at > $3;
+
+-- name: ClaimNextJob :one
+UPDATE jobs SET status = 'running', started_at = @now, updated_at = @now
+WHERE id = (
+  SELECT id FROM jobs
+  WHERE status = 'queued'
+  ORDER BY created_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: FinishJob :one
+UPDATE jobs SET status = $2, new_posts = $3, error = $4, finished_at = $5, updated_at = $5
+WHERE id = $1
+RETURNING *;
+
+-- name: FailStaleJobs :execrows
+-- Jobs left running by a server instance that stopped mid-job.
+UPDATE jobs SET status = 'failed', error = 'job was interrupted', finished_at = @now, updated_at = @now
+WHERE status = 'running' AND started_at < @started_before;
+
+-- name: LockUserJobs :exec
+-- Serializes the job limit checks of one user until the transaction ends.
+SELECT pg_advisory_xact_lock(hashtextextended('jobs:' || @user_id::uuid::text, 0));
@@ -0,0 +1,15 @@
+-- name: CreateOutputFeed :one
+INSERT INTO output_feeds (
+  id, created_at, updated_at, user_id, name, token, folder_id, query
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: GetOutputFeedsByUser :many
+SELECT * FROM output_feeds WHERE user_id = $1 ORDER BY created_at;
+
+-- name: GetOutputFeedByToken :one
+SELECT * FROM output_feeds WHERE token = $1;
+
+-- name: DeleteOutputFeed :execrows
+DELETE FROM output_feeds WHERE id = $1 AND user_id = $2;
@@ -1,15 +1,94 @@
 -- name: CreatePost :one
 INSERT INTO posts (
-  id, created_at, updated_at, feed_id, title, url, description, publish_date
+  id, created_at, updated_at, feed_id, title, url, description, publish_date, content
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
 ) RETURNING *;
 
 -- name: GetPostsByUser :many
+SELECT * FROM posts WHERE feed_id IN (SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = $1 AND NOT feed_follows.hidden) ORDER BY publish_date DESC OFFSET $2 LIMIT $3;
+
+-- name: GetPostsByFeedOwner :many
 SELECT * FROM posts WHERE feed_id IN (SELECT id FROM feeds WHERE user_id = $1) ORDER BY publish_date DESC OFFSET $2 LIMIT $3;
 
 -- name: GetPostByURL :one
 SELECT * FROM posts WHERE url = $1;
 
+-- name: GetPostByID :one
+SELECT * FROM posts WHERE id = $1;
+
 -- name: GetPostsByFeedID :many
 SELECT * FROM posts WHERE feed_id = $1 ORDER BY publish_date DESC OFFSET $2 LIMIT $3;
+
+-- name: GetPostsByFeedOwnerPage :many
+SELECT * FROM posts
+WHERE feed_id IN (SELECT id FROM feeds WHERE user_id = @user_id)
+  AND (sqlc.narg('before_date')::timestamptz IS NULL OR (publish_date, id) < (sqlc.narg('before_date')::timestamptz, sqlc.narg('before_id')::uuid))
+ORDER BY publish_date DESC, id DESC
+LIMIT @page_limit;
+
+-- name: GetPostsByFeedIDPage :many
+SELECT * FROM posts
+WHERE feed_id = @feed_id
+  AND (sqlc.narg('before_date')::timestamptz IS NULL OR (publish_date, id) < (sqlc.narg('before_date')::timestamptz, sqlc.narg('before_id')::uuid))
+ORDER BY publish_date DESC, id DESC
+LIMIT @page_limit;
+
+-- name: SearchPostsByUser :many
+SELECT
+  posts.id, posts.created_at, posts.updated_at, posts.feed_id, posts.title, posts.url, posts.description, posts.publish_date,
+  ts_rank_cd(posts.search_vector, query)::real AS rank,
+  -- tags are stripped and matches delimited by chr(2) and chr(3), search.Snippet
+  -- escapes the text and turns the delimiters into <mark>
+  ts_headline(
+    'english',
+    regexp_replace(posts.title || ' ' || posts.description || ' ' || posts.content, '<[^>]*>', ' ', 'g'),
+    query,
+    'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2, MaxWords=30, MinWords=10'
+  ) AS snippet
+FROM posts, to_tsquery('english', @query) query
+WHERE posts.feed_id IN (SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = @user_id AND NOT feed_follows.hidden)
+  AND posts.search_vector @@ query
+ORDER BY rank DESC, posts.publish_date DESC, posts.id DESC
+OFFSET @row_offset LIMIT @row_limit;
+
+-- name: GetFilteredPostsByUser :many
+SELECT * FROM posts
+WHERE feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = @user_id
+      AND (sqlc.narg('folder_id')::uuid IS NULL OR feed_follows.folder_id = sqlc.narg('folder_id'))
+      -- hidden feeds only show up when asked for by id
+      AND (NOT feed_follows.hidden OR feed_follows.feed_id = ANY(@feed_ids::uuid[]))
+  )
+  AND (sqlc.narg('since')::timestamptz IS NULL OR publish_date >= sqlc.narg('since'))
+  AND (sqlc.narg('until')::timestamptz IS NULL OR publish_date < sqlc.narg('until'))
+  AND (cardinality(@feed_ids::uuid[]) = 0 OR feed_id = ANY(@feed_ids::uuid[]))
+  AND (sqlc.narg('query')::text IS NULL OR search_vector @@ to_tsquery('english', sqlc.narg('query')))
+  AND (sqlc.narg('before_date')::timestamptz IS NULL OR (publish_date, id) < (sqlc.narg('before_date')::timestamptz, sqlc.narg('before_id')::uuid))
+ORDER BY publish_date DESC, id DESC
+LIMIT @page_limit;
+
+-- name: GetFilteredPostsByUserIngested :many
+SELECT * FROM posts
+WHERE feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = @user_id
+      AND (sqlc.narg('folder_id')::uuid IS NULL OR feed_follows.folder_id = sqlc.narg('folder_id'))
+      -- hidden feeds only show up when asked for by id
+      AND (NOT feed_follows.hidden OR feed_follows.feed_id = ANY(@feed_ids::uuid[]))
+  )
+  AND (sqlc.narg('since')::timestamptz IS NULL OR created_at >= sqlc.narg('since'))
+  AND (sqlc.narg('until')::timestamptz IS NULL OR created_at < sqlc.narg('until'))
+  AND (cardinality(@feed_ids::uuid[]) = 0 OR feed_id = ANY(@feed_ids::uuid[]))
+  AND (sqlc.narg('query')::text IS NULL OR search_vector @@ to_tsquery('english', sqlc.narg('query')))
+  AND (sqlc.narg('before_date')::timestamptz IS NULL OR (created_at, id) < (sqlc.narg('before_date')::timestamptz, sqlc.narg('before_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT @page_limit;
+
+-- name: GetPostsByUserCreatedAfter :many
+SELECT * FROM posts
+WHERE feed_id IN (SELECT feed_id FROM feed_follows WHERE feed_follows.user_id = @user_id AND NOT feed_follows.hidden)
+  AND (created_at, id) > (@after_date::timestamptz, @after_id::uuid)
+ORDER BY created_at, id
+LIMIT @page_limit;
@@ -0,0 +1,18 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, created_at, updated_at, user_id, token_hash, csrf_token, expires_at, last_seen_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetSessionByTokenHash :one
+SELECT * FROM sessions WHERE token_hash = @token_hash AND expires_at > @now;
+
+-- name: TouchSession :exec
+-- Records a use of the session, at most once a minute to spare a write per request.
+UPDATE sessions SET last_seen_at = @now, updated_at = @now
+WHERE id = @id AND last_seen_at < @now - interval '1 minute';
+
+-- name: DeleteSession :exec
+DELETE FROM sessions WHERE id = $1;
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions WHERE expires_at <= $1;
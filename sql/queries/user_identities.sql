@@ -0,0 +1,11 @@
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (id, created_at, updated_at, user_id, issuer, subject, email, last_login_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities WHERE issuer = $1 AND subject = $2;
+
+-- name: RecordUserIdentityLogin :exec
+UPDATE user_identities SET email = $3, last_login_at = $4, updated_at = $4
+WHERE issuer = $1 AND subject = $2;
@@ -1,16 +1,27 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
-VALUES ($1, $2, $3, $4)
+INSERT INTO users (id, created_at, updated_at, name, password_hash)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: CreateUserIfNameFree :one
+-- Returns no rows when the name is taken, which unlike an error keeps a transaction usable.
+INSERT INTO users (id, created_at, updated_at, name, password_hash)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (name) DO NOTHING
 RETURNING *;
 
 -- name: GetUserByName :one
 SELECT * FROM users WHERE name = $1;
 
--- name: GetUser :one
-SELECT * FROM users WHERE api_key = $1;
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1;
 
 -- name: GetUsers :many
-SELECT * FROM users;
+-- Lists users for administrators, optionally only those whose name contains query.
+SELECT * FROM users
+WHERE sqlc.narg('query')::text IS NULL OR name ILIKE '%' || sqlc.narg('query') || '%'
+ORDER BY created_at, id
+LIMIT sqlc.arg('row_limit') OFFSET sqlc.arg('row_offset');
 
 -- name: UpdateUser :one
 UPDATE users
@@ -18,5 +29,19 @@ SET updated_at = $2, name = $3
 WHERE id = $1
 RETURNING *;
 
--- name: DeleteUser :exec
+-- name: DeleteUser :execrows
 DELETE FROM users WHERE id = $1;
+
+-- name: SetUserAdmin :one
+UPDATE users SET is_admin = $2, updated_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserDisabled :one
+UPDATE users SET disabled_at = $2, updated_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: PromoteUsersToAdmin :execrows
+UPDATE users SET is_admin = true, updated_at = @updated_at
+WHERE id = ANY(sqlc.arg('ids')::uuid[]) AND NOT is_admin;
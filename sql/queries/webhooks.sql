@@ -0,0 +1,89 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (
+  id, created_at, updated_at, user_id, url, secret, feed_id, keyword
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: GetWebhooksByUser :many
+SELECT * FROM webhooks WHERE user_id = $1 ORDER BY created_at;
+
+-- name: GetWebhookByID :one
+SELECT * FROM webhooks WHERE id = $1 AND user_id = $2;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks WHERE id = $1;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks WHERE id = $1 AND user_id = $2;
+
+-- name: GetWebhooksForPost :many
+-- Webhooks of enabled users following the post's feed whose feed and keyword filters match.
+SELECT webhooks.* FROM webhooks
+JOIN users ON users.id = webhooks.user_id AND users.disabled_at IS NULL
+JOIN posts ON posts.id = @post_id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = webhooks.user_id AND feed_follows.notify
+WHERE (webhooks.feed_id IS NULL OR webhooks.feed_id = posts.feed_id)
+AND (webhooks.keyword IS NULL
+  OR position(lower(webhooks.keyword) IN lower(posts.title)) > 0
+  OR position(lower(webhooks.keyword) IN lower(posts.description)) > 0);
+
+-- name: CreateWebhookDelivery :one
+-- Returns sql.ErrNoRows when the post was already queued for the webhook.
+INSERT INTO webhook_deliveries (
+  id, created_at, updated_at, webhook_id, post_id, event, payload, next_attempt_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+) ON CONFLICT (webhook_id, post_id) DO NOTHING
+RETURNING *;
+
+-- name: ClaimWebhookDeliveries :many
+-- Leases due deliveries by pushing next_attempt_at past the delivery timeout,
+-- so other server instances skip them while they are being sent.
+UPDATE webhook_deliveries SET next_attempt_at = @lease_until, updated_at = @now
+WHERE id IN (
+  SELECT id FROM webhook_deliveries
+  WHERE status = 'pending' AND next_attempt_at <= @now
+    -- deliveries of disabled users wait until the account is enabled again
+    AND webhook_id IN (
+      SELECT webhooks.id FROM webhooks
+      JOIN users ON users.id = webhooks.user_id
+      WHERE users.disabled_at IS NULL
+    )
+  ORDER BY next_attempt_at
+  LIMIT @batch_size
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RecordWebhookDeliveryAttempt :one
+UPDATE webhook_deliveries SET
+  status = $2,
+  attempts = attempts + 1,
+  next_attempt_at = $3,
+  last_attempt_at = $4,
+  response_code = $5,
+  last_error = $6,
+  updated_at = $7
+WHERE id = $1
+RETURNING *;
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries WHERE webhook_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: GetWebhookSweep :one
+SELECT * FROM webhook_sweep;
+
+-- name: GetPostsCreatedAfter :many
+SELECT * FROM posts
+WHERE (created_at, id) > (@after_date::timestamptz, @after_id::uuid)
+  AND created_at < @before
+ORDER BY created_at, id
+LIMIT @page_limit;
+
+-- name: AdvanceWebhookSweep :exec
+-- Only moves the mark forward, so instances sweeping at the same time never move it back.
+UPDATE webhook_sweep SET swept_until = @swept_until, last_post_id = @last_post_id
+WHERE (swept_until, last_post_id) < (@swept_until::timestamptz, @last_post_id::uuid);
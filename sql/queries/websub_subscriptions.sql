@@ -0,0 +1,41 @@
+-- name: UpsertWebsubSubscription :one
+-- A renewal keeps the status, so pushes are accepted while the hub verifies it again.
+-- Asking again after a denial makes the subscription pending, so it can be verified.
+INSERT INTO websub_subscriptions (
+  feed_id, created_at, updated_at, hub_url, topic_url, secret
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) ON CONFLICT (feed_id) DO UPDATE SET
+  status = CASE WHEN websub_subscriptions.status = 'denied' THEN 'pending' ELSE websub_subscriptions.status END,
+  updated_at = EXCLUDED.updated_at,
+  hub_url = EXCLUDED.hub_url,
+  topic_url = EXCLUDED.topic_url,
+  secret = EXCLUDED.secret,
+  last_error = NULL
+RETURNING *;
+
+-- name: GetWebsubSubscription :one
+SELECT * FROM websub_subscriptions WHERE feed_id = $1;
+
+-- name: ActivateWebsubSubscription :execrows
+-- Only subscriptions we asked for are activated, a denied one stays denied.
+UPDATE websub_subscriptions SET status = 'active', lease_expires_at = $2, last_error = NULL, updated_at = $3
+WHERE feed_id = $1 AND status IN ('pending', 'active');
+
+-- name: DenyWebsubSubscription :exec
+UPDATE websub_subscriptions SET status = 'denied', lease_expires_at = NULL, last_error = $2, updated_at = $3
+WHERE feed_id = $1;
+
+-- name: SetWebsubSubscriptionError :exec
+UPDATE websub_subscriptions SET last_error = $2, updated_at = $3
+WHERE feed_id = $1;
+
+-- name: GetWebsubSubscriptionsToRenew :many
+-- Active subscriptions whose lease ends soon and pending ones the hub never verified.
+SELECT * FROM websub_subscriptions
+WHERE (status = 'active' AND lease_expires_at < @renew_before)
+OR (status = 'pending' AND updated_at < @stale_before);
+
+-- name: DeleteWebsubSubscription :exec
+-- Pushes for the feed are refused until it subscribes again.
+DELETE FROM websub_subscriptions WHERE feed_id = $1;
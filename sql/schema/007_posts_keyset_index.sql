@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE INDEX posts_publish_date_id_idx ON posts (publish_date DESC, id DESC);
+CREATE INDEX posts_feed_id_publish_date_id_idx ON posts (feed_id, publish_date DESC, id DESC);
+
+-- +goose Down
+DROP INDEX posts_feed_id_publish_date_id_idx;
+DROP INDEX posts_publish_date_id_idx;
+
+-- +goose Statement Comments
+-- This migration adds indexes backing keyset pagination on (publish_date, id).
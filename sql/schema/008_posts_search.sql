@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN content TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE posts ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', title), 'A') ||
+  setweight(to_tsvector('english', description), 'B') ||
+  setweight(to_tsvector('english', content), 'C')
+) STORED;
+
+CREATE INDEX posts_search_vector_idx ON posts USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX posts_search_vector_idx;
+ALTER TABLE posts DROP COLUMN search_vector;
+ALTER TABLE posts DROP COLUMN content;
+
+-- +goose Statement Comments
+-- This migration adds post content and a generated full-text search vector over title, description and content.
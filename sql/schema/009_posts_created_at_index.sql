@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE INDEX posts_created_at_id_idx ON posts (created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX posts_created_at_id_idx;
+
+-- +goose Statement Comments
+-- This migration adds an index for listing posts in ingestion order.
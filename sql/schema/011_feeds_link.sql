@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN link TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN link;
+
+-- +goose Statement Comments
+-- This migration stores the website link advertised by each feed's channel.
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE output_feeds (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  token VARCHAR(64) NOT NULL UNIQUE,
+  folder_id UUID REFERENCES folders(id) ON DELETE CASCADE,
+  query TEXT
+);
+
+-- +goose Down
+DROP TABLE output_feeds;
+
+-- +goose Statement Comments
+-- This migration creates output_feeds, which publish a user's timeline as RSS/Atom at a secret URL.
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE digest_settings (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL,
+  email TEXT NOT NULL,
+  frequency TEXT NOT NULL DEFAULT 'off' CHECK (frequency IN ('off', 'daily', 'weekly')),
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  send_hour INTEGER NOT NULL DEFAULT 8 CHECK (send_hour BETWEEN 0 AND 23),
+  send_weekday INTEGER NOT NULL DEFAULT 1 CHECK (send_weekday BETWEEN 0 AND 6),
+  last_sent_at TIMESTAMPTZ,
+  unsubscribe_token VARCHAR(64) NOT NULL UNIQUE
+);
+
+-- +goose Down
+DROP TABLE digest_settings;
+
+-- +goose Statement Comments
+-- This migration creates digest_settings, the per-user schedule for email digests.
+-- send_weekday follows Go's time.Weekday (0 is Sunday) and only applies to weekly digests.
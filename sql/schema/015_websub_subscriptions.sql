@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE websub_subscriptions (
+  feed_id UUID PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL,
+  hub_url TEXT NOT NULL,
+  topic_url TEXT NOT NULL,
+  secret TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending',
+  lease_expires_at TIMESTAMPTZ,
+  last_error TEXT
+);
+
+-- +goose Down
+DROP TABLE websub_subscriptions;
+
+-- +goose Statement Comments
+-- This migration creates websub_subscriptions, one per feed that advertises a WebSub hub.
+-- status is pending until the hub verifies the subscription, then active, or denied.
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE jobs (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'queued',
+  new_posts INTEGER,
+  error TEXT,
+  started_at TIMESTAMPTZ,
+  finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX jobs_queued_idx ON jobs (created_at) WHERE status = 'queued';
+CREATE INDEX jobs_feed_idx ON jobs (feed_id, created_at DESC);
+CREATE INDEX jobs_user_idx ON jobs (user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE jobs;
+
+-- +goose Statement Comments
+-- This migration creates jobs, background work on a feed requested by a user.
+-- status moves from queued to running to succeeded or failed.
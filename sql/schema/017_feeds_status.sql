@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+  ADD COLUMN status TEXT NOT NULL DEFAULT 'active',
+  ADD COLUMN status_error TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+  DROP COLUMN status,
+  DROP COLUMN status_error;
+
+-- +goose Statement Comments
+-- This migration adds feeds.status: a new feed is pending until a background job
+-- validates it, then active or failed with the reason in status_error.
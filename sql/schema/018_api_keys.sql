@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE api_keys (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  prefix VARCHAR(16) NOT NULL,
+  key_hash VARCHAR(64) NOT NULL UNIQUE,
+  last_used_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX api_keys_user_idx ON api_keys (user_id, created_at);
+
+-- existing keys keep working, only their hash is kept
+INSERT INTO api_keys (id, created_at, updated_at, user_id, name, prefix, key_hash)
+SELECT gen_random_uuid(), now(), now(), id, 'default', left(api_key, 8), encode(sha256(api_key::bytea), 'hex')
+FROM users;
+
+ALTER TABLE users DROP COLUMN api_key;
+
+-- +goose Down
+ALTER TABLE users
+ADD COLUMN api_key VARCHAR(64) NOT NULL UNIQUE DEFAULT encode(
+  sha256(random()::text::bytea),
+  'hex'
+);
+
+DROP TABLE api_keys;
+
+-- +goose Statement Comments
+-- This migration moves API keys to api_keys, which only stores a SHA-256 hash of each key
+-- and a short prefix to recognise it. Rolling back issues every user a new random key.
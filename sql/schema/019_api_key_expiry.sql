@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE api_keys
+ADD COLUMN expires_at TIMESTAMPTZ,
+ADD COLUMN replaced_by UUID REFERENCES api_keys(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE api_keys
+DROP COLUMN replaced_by,
+DROP COLUMN expires_at;
+
+-- +goose Statement Comments
+-- This migration adds an optional expiry date to API keys and links a rotated key to
+-- the key that replaced it. A rotated key keeps working until its expiry, the grace window.
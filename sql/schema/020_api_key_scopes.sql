@@ -0,0 +1,11 @@
+-- +goose Up
+-- existing keys keep full access
+ALTER TABLE api_keys ADD COLUMN scopes TEXT[] NOT NULL DEFAULT '{admin}';
+ALTER TABLE api_keys ALTER COLUMN scopes DROP DEFAULT;
+
+-- +goose Down
+ALTER TABLE api_keys DROP COLUMN scopes;
+
+-- +goose Statement Comments
+-- This migration limits what each API key may do with a list of scopes, such as
+-- posts:read or feeds:write. The admin scope grants everything.
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE user_identities (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  issuer TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  email TEXT,
+  last_login_at TIMESTAMPTZ NOT NULL,
+  UNIQUE(issuer, subject)
+);
+
+CREATE INDEX user_identities_user_idx ON user_identities (user_id);
+
+-- +goose Down
+DROP TABLE user_identities;
+
+-- +goose Statement Comments
+-- This migration links users to accounts at OpenID Connect identity providers. An
+-- identity is the issuer and subject of the ID token, the email is kept for reference.
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN disabled_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN disabled_at,
+DROP COLUMN is_admin;
+
+-- +goose Statement Comments
+-- This migration adds the administrator role and lets administrators disable accounts.
+-- A disabled user keeps their data but can no longer authenticate.
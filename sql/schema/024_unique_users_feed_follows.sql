@@ -0,0 +1,32 @@
+-- +goose Up
+-- duplicate names are renamed rather than merged, the oldest user keeps the name
+UPDATE users SET name = users.name || '-' || left(users.id::text, 8)
+FROM (
+  SELECT id, row_number() OVER (PARTITION BY name ORDER BY created_at, id) AS n
+  FROM users
+) ranked
+WHERE ranked.id = users.id AND ranked.n > 1;
+
+ALTER TABLE users ADD CONSTRAINT users_name_key UNIQUE (name);
+
+-- a user keeps one follow per feed, preferring the one filed in a folder
+DELETE FROM feed_follows
+USING (
+  SELECT id, row_number() OVER (
+    PARTITION BY feed_id, user_id ORDER BY folder_id IS NULL, created_at, id
+  ) AS n
+  FROM feed_follows
+) ranked
+WHERE ranked.id = feed_follows.id AND ranked.n > 1;
+
+ALTER TABLE feed_follows ADD CONSTRAINT feed_follows_feed_id_user_id_key UNIQUE (feed_id, user_id);
+
+-- +goose Down
+ALTER TABLE feed_follows DROP CONSTRAINT feed_follows_feed_id_user_id_key;
+
+ALTER TABLE users DROP CONSTRAINT users_name_key;
+
+-- +goose Statement Comments
+-- This migration makes user names and feed follows unique, which handlers only checked
+-- before inserting. Existing duplicate users get a suffixed name, duplicate follows are deleted.
+-- Rolling back keeps the renamed users and the deleted follows as they are.
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN title_override TEXT;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN title_override;
+
+-- +goose Statement Comments
+-- This migration lets the owner of a feed replace the channel title. title keeps holding
+-- the title to display, title_override the owner's choice so fetches do not overwrite it.
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE feed_follows
+  ADD COLUMN display_name TEXT,
+  ADD COLUMN notify BOOLEAN NOT NULL DEFAULT TRUE,
+  ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE feed_follows
+  DROP COLUMN display_name,
+  DROP COLUMN notify,
+  DROP COLUMN hidden;
+
+-- +goose Statement Comments
+-- This migration adds per-user settings to feed follows. display_name replaces the feed title
+-- for the follower only, notify turns digests and webhooks for the feed off, and hidden keeps
+-- its posts out of the timeline while the feed stays followed.
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feed_follows ADD COLUMN last_read_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN last_read_at;
+
+-- +goose Statement Comments
+-- This migration adds a read marker to feed follows. Posts ingested after last_read_at count as
+-- unread for the follower, so existing and new follows start with nothing unread.
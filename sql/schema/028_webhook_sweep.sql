@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE webhook_sweep (
+  id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+  swept_until TIMESTAMPTZ NOT NULL,
+  last_post_id UUID NOT NULL
+);
+
+INSERT INTO webhook_sweep (swept_until, last_post_id)
+VALUES (now(), '00000000-0000-0000-0000-000000000000');
+
+-- +goose Down
+DROP TABLE webhook_sweep;
+
+-- +goose Statement Comments
+-- This migration adds the high-water mark of the webhook sweep, a single row holding the
+-- created_at and id of the last post that deliveries were queued for. Posts are still queued
+-- from post.created events, the sweep queues the ones whose event was dropped or lost.
@@ -0,0 +1,19 @@
+-- +goose Up
+-- only the newest of duplicate refreshes stays in progress
+UPDATE jobs SET status = 'failed', error = 'duplicate refresh', finished_at = now(), updated_at = now()
+FROM (
+  SELECT id, row_number() OVER (PARTITION BY feed_id ORDER BY created_at DESC, id) AS n
+  FROM jobs
+  WHERE kind = 'refresh' AND status IN ('queued', 'running')
+) ranked
+WHERE ranked.id = jobs.id AND ranked.n > 1;
+
+CREATE UNIQUE INDEX jobs_refresh_in_progress_idx ON jobs (feed_id)
+WHERE kind = 'refresh' AND status IN ('queued', 'running');
+
+-- +goose Down
+DROP INDEX jobs_refresh_in_progress_idx;
+
+-- +goose Statement Comments
+-- This migration allows one queued or running refresh per feed, which the refresh handler
+-- only checked before inserting. Rolling back keeps the failed duplicates as they are.
@@ -1,8 +1,15 @@
 import React, { createContext, ReactNode, useContext, useState } from "react";
+import axios from "axios";
+
+// the session cookie has to be sent along with cross-origin API calls
+axios.defaults.withCredentials = true;
 
 type AuthContextType = {
   apiKey: string;
   setApiKey: (key: string) => Promise<void>;
+  login: (name: string, password: string) => Promise<void>;
+  register: (name: string, password: string) => Promise<void>;
+  authHeaders: () => Record<string, string>;
   isLoggedIn: boolean;
   LoginError: string;
   setLoginError: (error: string) => void;
@@ -23,6 +30,8 @@ export const AuthProvider: React.FC<{ children: ReactNode }> = ({
   children,
 }) => {
   const [apiKey, setApiKey] = useState<string>("");
+  // set when logged in with a password, the session itself is an HttpOnly cookie
+  const [csrfToken, setCsrfToken] = useState<string>("");
   const [isLoggedIn, setIsLoggedIn] = useState<boolean>(false);
   const [LoginError, setLoginError] = useState<string>("");
 
@@ -35,6 +44,59 @@ export const AuthProvider: React.FC<{ children: ReactNode }> = ({
   //   }
   // }, []);
 
+  // resume the session of an earlier password login
+  React.useEffect(() => {
+    fetch("http://localhost:8080/v1/auth/session", { credentials: "include" })
+      .then((response) => (response.ok ? response.json() : null))
+      .then((data) => {
+        if (data) {
+          setCsrfToken(data.csrf_token);
+          setIsLoggedIn(true);
+        }
+      })
+      .catch((error) => console.error("Error checking session", error));
+  }, []);
+
+  // authHeaders authenticates a request with the API key, or with the session
+  // cookie when logged in with a password. Requests using the cookie must be
+  // sent with credentials and carry the CSRF token.
+  const authHeaders = (): Record<string, string> => {
+    if (apiKey) {
+      return { Authorization: `Bearer ${apiKey}` };
+    }
+    if (csrfToken) {
+      return { "X-CSRF-Token": csrfToken };
+    }
+    return {};
+  };
+
+  const startSession = async (path: string, name: string, password: string) => {
+    const response = await fetch(`http://localhost:8080/v1/auth/${path}`, {
+      method: "POST",
+      credentials: "include",
+      headers: {
+        "Content-Type": "application/json",
+      },
+      body: JSON.stringify({ name, password }),
+    });
+    const data = await response.json();
+    if (!response.ok) {
+      setLoginError(data.error);
+      throw new Error(data.error);
+    }
+
+    console.log(`Login is successful for user: ${data.user.name}`);
+    setCsrfToken(data.csrf_token);
+    setIsLoggedIn(true);
+    setLoginError("");
+  };
+
+  const handleLogin = (name: string, password: string) =>
+    startSession("login", name, password);
+
+  const handleRegister = (name: string, password: string) =>
+    startSession("register", name, password);
+
   const handleSetApiKey = async (key: string) => {
     try {
       const response = await fetch("http://localhost:8080/v1/users", {
@@ -65,6 +127,14 @@ export const AuthProvider: React.FC<{ children: ReactNode }> = ({
   };
 
   const handleLogout = () => {
+    if (csrfToken) {
+      fetch("http://localhost:8080/v1/auth/logout", {
+        method: "POST",
+        credentials: "include",
+        headers: { "X-CSRF-Token": csrfToken },
+      }).catch((error) => console.error("Error logging out", error));
+    }
+    setCsrfToken("");
     setApiKey("");
     setIsLoggedIn(false);
     setLoginError("");
@@ -78,6 +148,9 @@ export const AuthProvider: React.FC<{ children: ReactNode }> = ({
       value={{
         apiKey,
         setApiKey: handleSetApiKey,
+        login: handleLogin,
+        register: handleRegister,
+        authHeaders,
         handleLogout,
         isLoggedIn,
         LoginError,
@@ -18,17 +18,21 @@ interface Feed {
   title: string;
   url: string;
   follow: boolean;
+  // set on followed feeds when the user renamed the feed for themselves
+  display_name?: { String: string; Valid: boolean };
 }
 
 const fetchFeeds = async (
   isLoggedIn: boolean,
   showAll: boolean,
-  apiKey: string
+  headers: Record<string, string>
 ): Promise<Feed[]> => {
   if (isLoggedIn) {
     const followsUrl = `http://localhost:8080/v1/feed_follows`;
-    const headers = { Authorization: `Bearer ${apiKey}` };
-    const followedFeedsResponse = await fetchWithRetry(followsUrl, { headers });
+    const followedFeedsResponse = await fetchWithRetry(followsUrl, {
+      headers,
+      credentials: "include",
+    });
     if (!followedFeedsResponse.ok) {
       throw new Error(`HTTP error! status: ${followedFeedsResponse.status}`);
     }
@@ -43,10 +47,11 @@ const fetchFeeds = async (
         throw new Error(`HTTP error! status: ${allFeedsResponse.status}`);
       }
       const allFeeds: Feed[] = await allFeedsResponse.json();
-      const followedIds = new Set(followedFeeds.map((feed) => feed.id));
+      const followed = new Map(followedFeeds.map((feed) => [feed.id, feed]));
       return allFeeds.map((feed) => ({
         ...feed,
-        follow: followedIds.has(feed.id),
+        display_name: followed.get(feed.id)?.display_name,
+        follow: followed.has(feed.id),
       }));
     }
   } else {
@@ -84,16 +89,18 @@ const FeedList: React.FC<{ showAll: boolean }> = ({ showAll }) => {
   const [feeds, setFeeds] = useState<Feed[]>([]);
   const [fetchError, setFetchError] = useState<string>("");
   const [isFormVisible, setIsFormVisible] = useState(false);
-  const { apiKey, isLoggedIn } = useAuth();
+  const { apiKey, isLoggedIn, authHeaders } = useAuth();
   const navigate = useNavigate();
 
   useEffect(() => {
-    fetchFeeds(isLoggedIn, showAll, apiKey)
+    fetchFeeds(isLoggedIn, showAll, authHeaders())
       .then(setFeeds)
       .catch((error) => {
         console.error("Error fetching feeds", error);
         setFetchError("Error fetching / refreshing feeds");
       });
+    // authHeaders only changes with the login, tracked by apiKey and isLoggedIn
+    // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [apiKey, isLoggedIn, showAll]);
 
   const toggleFormVisibility = () => {
@@ -102,7 +109,7 @@ const FeedList: React.FC<{ showAll: boolean }> = ({ showAll }) => {
 
   const handleFeedFollow = async (feed_id: string) => {
     if (!isLoggedIn) {
-      toast.error("Please login with an API key to follow a feed", {
+      toast.error("Please login to follow a feed", {
         position: "top-center",
         autoClose: 1500,
       });
@@ -116,7 +123,7 @@ const FeedList: React.FC<{ showAll: boolean }> = ({ showAll }) => {
         {
           headers: {
             "Content-Type": "application/json",
-            Authorization: `Bearer ${apiKey}`,
+            ...authHeaders(),
           },
         }
       );
@@ -141,7 +148,7 @@ const FeedList: React.FC<{ showAll: boolean }> = ({ showAll }) => {
         {
           headers: {
             "Content-Type": "application/json",
-            Authorization: `Bearer ${apiKey}`,
+            ...authHeaders(),
           },
         }
       );
@@ -186,7 +193,9 @@ const FeedList: React.FC<{ showAll: boolean }> = ({ showAll }) => {
           .map((feed) => (
             <li key={feed.id}>
               <h3>
-                {feed.title}{" "}
+                {feed.display_name?.Valid
+                  ? feed.display_name.String
+                  : feed.title}{" "}
                 <a href={feed.url || "#"} target="_blank" rel="noreferrer">
                   <FaLink />
                 </a>{" "}
@@ -10,8 +10,11 @@ import { toast } from "react-toastify";
 
 export default function LoginForm() {
   const [showPassword, setShowPassword] = useState(false);
+  const [useApiKey, setUseApiKey] = useState(false);
+  const [nameInput, setNameInput] = useState("");
+  const [passwordInput, setPasswordInput] = useState("");
   const [apiKeyInput, setApiKeyInput] = useState("");
-  const { setApiKey, LoginError, setLoginError } = useAuth();
+  const { setApiKey, login, LoginError, setLoginError } = useAuth();
   const navigate = useNavigate();
 
   const handleLoginSuccess = () => {
@@ -36,7 +39,11 @@ export default function LoginForm() {
   const handleLogin = async (event: React.FormEvent<HTMLFormElement>) => {
     event.preventDefault();
     try {
-      await setApiKey(apiKeyInput); // Update the global API key
+      if (useApiKey) {
+        await setApiKey(apiKeyInput); // Update the global API key
+      } else {
+        await login(nameInput, passwordInput);
+      }
       handleLoginSuccess();
     } catch (error) {
       console.error("Error logging in", error);
@@ -61,6 +68,32 @@ export default function LoginForm() {
     }
   };
 
+  const handleNameChange = (event: React.ChangeEvent<HTMLInputElement>) => {
+    setNameInput(event.target.value);
+    if (LoginError) {
+      setLoginError("");
+    }
+  };
+
+  const handlePasswordChange = (
+    event: React.ChangeEvent<HTMLInputElement>
+  ) => {
+    setPasswordInput(event.target.value);
+    if (LoginError) {
+      setLoginError("");
+    }
+  };
+
+  // the API redirects back to the webui, which resumes the session from its cookie
+  const handleSingleSignOn = () => {
+    window.location.href = "http://localhost:8080/v1/auth/oidc/login";
+  };
+
+  const toggleLoginMethod = () => {
+    setUseApiKey(!useApiKey);
+    setLoginError("");
+  };
+
   return (
     <form
       onSubmit={handleLogin}
@@ -72,35 +105,81 @@ export default function LoginForm() {
         margin: "0 auto",
       }}
     >
-      <TextField
-        label="API Key"
-        variant="outlined"
-        onChange={handleApikeyChange}
-        error={LoginError ? true : false}
-        name="apikey"
-        margin="normal"
-        type={showPassword ? "text" : "password"}
-        required={true}
-        fullWidth
-        InputProps={{
-          endAdornment: (
-            <InputAdornment position="end">
-              <IconButton
-                aria-label="toggle password visibility"
-                onClick={handleClickShowPassword}
-                onMouseDown={handleMouseDownPassword}
-                edge="end"
-              >
-                {showPassword ? <VisibilityOff /> : <Visibility />}
-              </IconButton>
-            </InputAdornment>
-          ),
-        }}
-      />
+      {useApiKey ? (
+        <TextField
+          label="API Key"
+          variant="outlined"
+          onChange={handleApikeyChange}
+          error={LoginError ? true : false}
+          name="apikey"
+          margin="normal"
+          type={showPassword ? "text" : "password"}
+          required={true}
+          fullWidth
+          InputProps={{
+            endAdornment: (
+              <InputAdornment position="end">
+                <IconButton
+                  aria-label="toggle password visibility"
+                  onClick={handleClickShowPassword}
+                  onMouseDown={handleMouseDownPassword}
+                  edge="end"
+                >
+                  {showPassword ? <VisibilityOff /> : <Visibility />}
+                </IconButton>
+              </InputAdornment>
+            ),
+          }}
+        />
+      ) : (
+        <>
+          <TextField
+            label="Name"
+            variant="outlined"
+            onChange={handleNameChange}
+            error={LoginError ? true : false}
+            name="username"
+            margin="normal"
+            required={true}
+            fullWidth
+          />
+          <TextField
+            label="Password"
+            variant="outlined"
+            onChange={handlePasswordChange}
+            error={LoginError ? true : false}
+            name="password"
+            margin="normal"
+            type={showPassword ? "text" : "password"}
+            required={true}
+            fullWidth
+            InputProps={{
+              endAdornment: (
+                <InputAdornment position="end">
+                  <IconButton
+                    aria-label="toggle password visibility"
+                    onClick={handleClickShowPassword}
+                    onMouseDown={handleMouseDownPassword}
+                    edge="end"
+                  >
+                    {showPassword ? <VisibilityOff /> : <Visibility />}
+                  </IconButton>
+                </InputAdornment>
+              ),
+            }}
+          />
+        </>
+      )}
       {LoginError && <p style={{ color: "red" }}>{LoginError}</p>}
       <button className="login-button" type="submit">
         Log In
       </button>
+      <button type="button" onClick={toggleLoginMethod}>
+        {useApiKey ? "Log in with a password" : "Log in with an API key"}
+      </button>
+      <button type="button" onClick={handleSingleSignOn}>
+        Log in with single sign-on
+      </button>
 
       <hr style={{ margin: "16px 0" }} />
       <button className="signup-button" type="button" onClick={handleSignUp}>
@@ -3,13 +3,33 @@ import axios from "axios";
 import { toast } from "react-toastify";
 import { useAuth } from "./AuthContext";
 
+const jobPollInterval = 1000;
+const jobPollAttempts = 120;
+
+const waitForJob = async (jobId: string, headers: Record<string, string>) => {
+  for (let i = 0; i < jobPollAttempts; i++) {
+    const response = await axios.get(
+      `http://localhost:8080/v1/jobs/${jobId}`,
+      { headers }
+    );
+    if (
+      response.data.status === "succeeded" ||
+      response.data.status === "failed"
+    ) {
+      return response.data;
+    }
+    await new Promise((resolve) => setTimeout(resolve, jobPollInterval));
+  }
+  throw new Error("Timed out waiting for job " + jobId);
+};
+
 const FeedForm: React.FC = () => {
   const [url, setUrl] = useState("");
-  const { apiKey } = useAuth();
+  const { isLoggedIn, authHeaders } = useAuth();
 
   const handleSubmit = async (event: React.FormEvent) => {
-    if (!apiKey) {
-      toast.error("Please login with an API key to follow a feed", {
+    if (!isLoggedIn) {
+      toast.error("Please login to follow a feed", {
         position: "top-center",
         autoClose: 3000,
       });
@@ -25,26 +45,36 @@ const FeedForm: React.FC = () => {
       const response = await axios.post(
         "http://localhost:8080/v1/feeds",
         payload,
-        {
-          headers: {
-            Authorization: `Bearer ${apiKey}`,
-          },
-        }
+        { headers: authHeaders() }
       );
 
-      if (response.status !== 201) {
+      if (response.status !== 202) {
         throw new Error(
           "Feed not created" + response.status + response.statusText
         );
       }
 
-      toast.success("Feed added successfully", {
+      toast.info("Feed added, fetching its posts...", {
         position: "top-center",
         autoClose: 3000,
       });
 
-      // Handle response here
-      // console.log(response.data);
+      // the feed is validated in the background, follow its job until it is done
+      const job = await waitForJob(response.data.job.id, authHeaders());
+      if (job.status === "succeeded") {
+        toast.success(`Feed ready with ${job.new_posts.Int32} posts`, {
+          position: "top-center",
+          autoClose: 3000,
+        });
+      } else {
+        toast.error(
+          "Failed to fetch feed data, check if the URL is valid and try again.",
+          {
+            position: "top-center",
+            autoClose: 3000,
+          }
+        );
+      }
     } catch (error) {
       // Handle error here
       console.error(error);
@@ -5,7 +5,8 @@ import { toast } from "react-toastify";
 
 export default function LoginForm() {
   const [userNameInput, setUserNameInput] = useState("");
-  const { setApiKey, LoginError, setLoginError } = useAuth();
+  const [passwordInput, setPasswordInput] = useState("");
+  const { register, LoginError, setLoginError } = useAuth();
 
   const handleLoginSuccess = () => {
     toast.success("Login was successful!", {
@@ -25,27 +26,8 @@ export default function LoginForm() {
   const handleLogin = async (event: React.FormEvent<HTMLFormElement>) => {
     event.preventDefault();
     try {
-      //call the users endpoint to get the user's API key, payload must be a JSON object with a name key
-      const response = await fetch("http://localhost:8080/v1/users", {
-        method: "POST",
-        headers: {
-          "Content-Type": "application/json",
-        },
-        body: JSON.stringify({ name: userNameInput }),
-      });
-
-      if (response.status !== 201) {
-        throw new Error(
-          "User not created" + response.status + response.statusText
-        );
-      }
-      const data = await response.json();
-
-      console.log(data);
-
-      const apiKey = data.api_key;
-
-      await setApiKey(apiKey); // Update the global API key
+      // creates the account and logs it in with a session cookie
+      await register(userNameInput, passwordInput);
       handleLoginSuccess();
     } catch (error) {
       console.error("Error logging in", error);
@@ -53,6 +35,15 @@ export default function LoginForm() {
     }
   };
 
+  const handlePasswordChange = (
+    event: React.ChangeEvent<HTMLInputElement>
+  ) => {
+    setPasswordInput(event.target.value);
+    if (LoginError) {
+      setLoginError("");
+    }
+  };
+
   const handleUsernameChange = (event: React.ChangeEvent<HTMLInputElement>) => {
     setUserNameInput(event.target.value);
     //clear the error message when the user starts typing
@@ -82,6 +73,18 @@ export default function LoginForm() {
         required={true}
         fullWidth
       />
+      <TextField
+        label="password"
+        variant="outlined"
+        onChange={handlePasswordChange}
+        error={LoginError ? true : false}
+        name="password"
+        type="password"
+        margin="normal"
+        required={true}
+        fullWidth
+        helperText="At least 8 characters"
+      />
       {LoginError && <p style={{ color: "red" }}>{LoginError}</p>}
       <button className="signup-button" type="submit">
         Create new account
@@ -13,7 +13,7 @@ const PostList: React.FC<{
   const [isFetching, setIsFetching] = useState(false);
   const [hasMore, setHasMore] = useState(true);
   const loaderRef = useRef<HTMLLIElement | null>(null);
-  const { apiKey, isLoggedIn } = useAuth();
+  const { apiKey, isLoggedIn, authHeaders } = useAuth();
 
   useEffect(() => {
     const observer = new IntersectionObserver(
@@ -50,7 +50,8 @@ const PostList: React.FC<{
         const params = new URLSearchParams({ offset, limit: initialLimit });
         url.search = params.toString();
         const response = await fetchWithRetry(url.toString(), {
-          headers: isLoggedIn ? { Authorization: `Bearer ${apiKey}` } : {},
+          headers: isLoggedIn ? authHeaders() : {},
+          credentials: "include",
         });
         if (response.ok) {
           const newPosts: Post[] = await response.json();
@@ -85,6 +86,8 @@ const PostList: React.FC<{
     };
 
     fetchPosts();
+    // authHeaders only changes with the login, tracked by apiKey and isLoggedIn
+    // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [apiKey, feed_id, initialLimit, isLoggedIn, offset]);
 
   const fetchWithRetry = async (
@@ -8,7 +8,7 @@ export default function LoginPage() {
     <>
       {!isLoggedIn ? (
         <div style={{ margin: "20px auto", textAlign: "center" }}>
-          <h3>Log in with your API key to see posts from your curated list</h3>
+          <h3>Log in to see posts from your curated list</h3>
           <LoginForm />
         </div>
       ) : (
@@ -2,16 +2,13 @@ import { useAuth } from "../components/AuthContext";
 import NewUserForm from "../components/NewUserForm";
 
 export default function LoginPage() {
-  const { isLoggedIn, handleLogout, apiKey } = useAuth();
+  const { isLoggedIn, handleLogout } = useAuth();
 
   return (
     <>
       {!isLoggedIn ? (
         <div style={{ margin: "20px auto", textAlign: "center" }}>
-          <h3>
-            Create an account to get the API key to see posts from your curated
-            list
-          </h3>
+          <h3>Create an account to see posts from your curated list</h3>
           <NewUserForm />
         </div>
       ) : (
@@ -19,12 +16,9 @@ export default function LoginPage() {
           You are already logged in.{" "}
           <p>
             {" "}
-            Your API Key is: <code>{apiKey}</code>
-          </p>
-          <p>
-            {" "}
-            Please copy it and keep it safe, you will not be able to retrieve
-            this key later
+            Programmatic clients can use an API key, create one with{" "}
+            <code>POST /v1/api_keys</code> and the <code>scopes</code> it
+            needs, like <code>["posts:read"]</code> for a read-only key
           </p>
           <button onClick={handleLogout}>Log out</button>
         </div>